@@ -1,8 +1,11 @@
 package bunrouter
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
 	"testing"
+	"time"
 )
 
 func BenchmarkRouterSimple(b *testing.B) {
@@ -94,3 +97,128 @@ func benchRequest(b *testing.B, router http.Handler, r *http.Request) {
 		router.ServeHTTP(w, r)
 	}
 }
+
+func BenchmarkParamsMap(b *testing.B) {
+	router := New()
+
+	var params map[string]string
+	router.GET("/:a/:b/:c/:d/:e", func(w http.ResponseWriter, req Request) error {
+		params = req.Params().Map()
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/test/test/test/test/test", nil)
+
+	benchRequest(b, router, req)
+	_ = params
+}
+
+func BenchmarkParamsSlice(b *testing.B) {
+	router := New()
+
+	var params []Param
+	router.GET("/:a/:b/:c/:d/:e", func(w http.ResponseWriter, req Request) error {
+		params = req.Params().Slice()
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/test/test/test/test/test", nil)
+
+	benchRequest(b, router, req)
+	_ = params
+}
+
+func BenchmarkURL(b *testing.B) {
+	router := New()
+	router.GET("/users/:id<[0-9]+>/posts/:post", simpleHandler, WithRouteName("post"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := router.URL("post", "id", 42, "post", "hello"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParamsByName(b *testing.B) {
+	router := New()
+
+	var value string
+	router.GET("/user/:name/:resource", func(w http.ResponseWriter, req Request) error {
+		value = req.Param("name") + req.Param("resource")
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/user/aaaabbbbccccddddeeeeffff/asdfghjkl", nil)
+
+	benchRequest(b, router, req)
+	_ = value
+}
+
+// largeRouter returns a router with n static routes and n param routes,
+// for example, "/api/v1/items123/status" and "/api/v1/items123/:id".
+func largeRouter(n int, opts ...Option) *Router {
+	router := New(opts...)
+	for i := 0; i < n; i++ {
+		router.GET(fmt.Sprintf("/api/v1/items%d/status", i), simpleHandler)
+		router.GET(fmt.Sprintf("/api/v1/items%d/:id", i), simpleHandler)
+	}
+	return router
+}
+
+// benchLookups looks up the routes for the requests in turn and reports
+// the 99th percentile latency of matching.
+func benchLookups(b *testing.B, router *Router, reqs []*http.Request) {
+	durs := make([]time.Duration, b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		var params Params
+		_ = router.lookup(nil, reqs[i%len(reqs)], &params)
+		durs[i] = time.Since(start)
+	}
+
+	b.StopTimer()
+	sort.Slice(durs, func(i, j int) bool { return durs[i] < durs[j] })
+	b.ReportMetric(float64(durs[len(durs)*99/100].Nanoseconds()), "p99-ns")
+}
+
+func largeRequests(n int, format string) []*http.Request {
+	reqs := make([]*http.Request, 0, 1000)
+	for i := 0; i < cap(reqs); i++ {
+		req, _ := http.NewRequest("GET", fmt.Sprintf(format, i*(n/cap(reqs))), nil)
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+func BenchmarkLargeRouteTable(b *testing.B) {
+	const n = 25000
+
+	router := largeRouter(n)
+	cachedRouter := largeRouter(n, WithLookupCache(4096))
+
+	b.Run("static", func(b *testing.B) {
+		benchLookups(b, router, largeRequests(n, "/api/v1/items%d/status"))
+	})
+	b.Run("param", func(b *testing.B) {
+		benchLookups(b, router, largeRequests(n, "/api/v1/items%d/123"))
+	})
+	b.Run("not found", func(b *testing.B) {
+		benchLookups(b, router, largeRequests(n, "/api/v1/items%d/status/1/2"))
+	})
+	b.Run("not found cached", func(b *testing.B) {
+		benchLookups(b, cachedRouter, largeRequests(n, "/api/v1/items%d/status/1/2"))
+	})
+	b.Run("redirect", func(b *testing.B) {
+		benchLookups(b, router, largeRequests(n, "/api/v1/items%d/status/"))
+	})
+	b.Run("redirect cached", func(b *testing.B) {
+		benchLookups(b, cachedRouter, largeRequests(n, "/api/v1/items%d/status/"))
+	})
+}
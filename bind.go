@@ -0,0 +1,397 @@
+package bunrouter
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMaxMemory is the max size of a multipart form kept in memory, the same as in net/http.
+const defaultMaxMemory = 32 << 20
+
+// Bind fills the struct pointed to by dst with values from the request using struct tags:
+//
+//   - `path:"id"` for route params,
+//   - `query:"page"` for query string params,
+//   - `header:"X-Tenant"` for request headers,
+//   - `form:"name"` for url-encoded and multipart form fields,
+//   - `json:"name"` for the JSON request body.
+//
+// The JSON body is decoded first, so values from other sources take precedence.
+// Fields bound from the path, query, headers, or form are not decoded from the JSON
+// body unless they also have a json tag, so clients can't set them in the body.
+// Fields without a value in the request are left unchanged. Slices are filled from
+// all values of a query param, header, or form field. Field types are the same as
+// supported by ParamAs. Embedded structs are bound as well.
+//
+// When values can't be parsed, Bind returns *BindError with all failed fields.
+// Otherwise, the struct is checked with ValidateStruct.
+func Bind(req Request, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bunrouter: Bind requires a non-nil pointer to a struct, got %T", dst)
+	}
+	v = v.Elem()
+
+	fields, err := structBindFields(v.Type())
+	if err != nil {
+		return err
+	}
+
+	b := binder{req: req}
+
+	b.decodeBody(v, fields)
+	for i := range fields {
+		b.bindField(v, &fields[i])
+	}
+
+	if len(b.errs) > 0 {
+		return &BindError{Errors: b.errs}
+	}
+	return ValidateStruct(dst)
+}
+
+// BindError is returned by Bind when request values can't be bound to the struct fields.
+// It implements HTTPError with the status code 400 Bad Request.
+type BindError struct {
+	Errors []*FieldError
+}
+
+var _ HTTPError = (*BindError)(nil)
+
+func (e *BindError) Error() string {
+	var b strings.Builder
+	b.WriteString("bunrouter: can't bind request: ")
+	for i, err := range e.Errors {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (e *BindError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+func (e *BindError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// FieldError describes a struct field that can't be bound.
+type FieldError struct {
+	Field  string // struct field name, for example, "UserID"
+	Source string // "path", "query", "header", "form", or "json"
+	Name   string // name in the source, for example, "user_id"
+	Value  string // value in the source
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%s: %s", e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s %q: %s", e.Source, e.Name, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+//------------------------------------------------------------------------------
+
+// bindSources are the struct tags used by Bind in the order they are applied.
+var bindSources = []string{"path", "query", "header", "form"}
+
+// bindField is a struct field bound from the request.
+type bindField struct {
+	index  []int
+	field  string
+	source string
+	name   string
+	json   bool // the field has a json tag and can be decoded from the JSON body
+}
+
+// bindFields caches bind fields by the struct type.
+var bindFields sync.Map
+
+func structBindFields(typ reflect.Type) ([]bindField, error) {
+	if fields, ok := bindFields.Load(typ); ok {
+		return fields.([]bindField), nil
+	}
+
+	var fields []bindField
+	if err := appendBindFields(&fields, typ, nil); err != nil {
+		return nil, err
+	}
+	bindFields.Store(typ, fields)
+	return fields, nil
+}
+
+func appendBindFields(fields *[]bindField, typ reflect.Type, index []int) error {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := appendBindFields(fields, f.Type, append(index[:len(index):len(index)], i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		for _, source := range bindSources {
+			name, _, _ := strings.Cut(f.Tag.Get(source), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if !canBind(f.Type) {
+				return fmt.Errorf("bunrouter: can't bind field %s of type %s", f.Name, f.Type)
+			}
+			*fields = append(*fields, bindField{
+				index:  append(index[:len(index):len(index)], i),
+				field:  f.Name,
+				source: source,
+				name:   name,
+				json:   jsonName != "" && jsonName != "-",
+			})
+		}
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// canBind reports whether a field of the type can be bound from strings.
+func canBind(typ reflect.Type) bool {
+	if _, ok := paramParsers.Load(typ); ok {
+		return true
+	}
+	if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Ptr, reflect.Slice:
+		return canBind(typ.Elem())
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+type binder struct {
+	req  Request
+	errs []*FieldError
+
+	query  map[string][]string
+	parsed bool
+}
+
+// decodeBody decodes the JSON body into the struct or parses the form body
+// when the struct has form fields.
+func (b *binder) decodeBody(strct reflect.Value, fields []bindField) {
+	req := b.req.Request
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		b.decodeJSON(strct, fields)
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data":
+		if !hasSource(fields, "form") {
+			return
+		}
+		var err error
+		if mediaType == "multipart/form-data" {
+			err = req.ParseMultipartForm(defaultMaxMemory)
+		} else {
+			err = req.ParseForm()
+		}
+		if err != nil {
+			b.errs = append(b.errs, &FieldError{Source: "form", Err: err})
+		}
+	}
+}
+
+// decodeJSON decodes the JSON body into a copy of the struct where the fields
+// bound from other sources are zeroed, so the body can't set them.
+func (b *binder) decodeJSON(strct reflect.Value, fields []bindField) {
+	tmp := reflect.New(strct.Type()).Elem()
+	tmp.Set(strct)
+	for i := range fields {
+		if f := &fields[i]; !f.json {
+			field := tmp.FieldByIndex(f.index)
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+
+	if err := json.NewDecoder(b.req.Body).Decode(tmp.Addr().Interface()); err != nil && err != io.EOF {
+		b.addJSONError(err)
+	}
+
+	for i := range fields {
+		if f := &fields[i]; !f.json {
+			tmp.FieldByIndex(f.index).Set(strct.FieldByIndex(f.index))
+		}
+	}
+	strct.Set(tmp)
+}
+
+func (b *binder) addJSONError(err error) {
+	fieldErr := &FieldError{Source: "json", Err: err}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		fieldErr.Name = typeErr.Field
+	}
+	b.errs = append(b.errs, fieldErr)
+}
+
+func hasSource(fields []bindField, source string) bool {
+	for i := range fields {
+		if fields[i].source == source {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *binder) bindField(strct reflect.Value, f *bindField) {
+	values := b.values(f)
+	if len(values) == 0 {
+		return
+	}
+
+	v := strct.FieldByIndex(f.index)
+	if value, err := setValue(v, values); err != nil {
+		b.errs = append(b.errs, &FieldError{
+			Field:  f.field,
+			Source: f.source,
+			Name:   f.name,
+			Value:  value,
+			Err:    err,
+		})
+	}
+}
+
+func (b *binder) values(f *bindField) []string {
+	switch f.source {
+	case "path":
+		if value, ok := b.req.Params().Get(f.name); ok && value != "" {
+			return []string{value}
+		}
+	case "query":
+		if !b.parsed {
+			b.query = b.req.URL.Query()
+			b.parsed = true
+		}
+		return b.query[f.name]
+	case "header":
+		return b.req.Header.Values(f.name)
+	case "form":
+		return b.req.PostForm[f.name]
+	}
+	return nil
+}
+
+// setValue sets the field from the values. It returns the value that can't be parsed.
+func setValue(v reflect.Value, values []string) (string, error) {
+	if _, ok := paramParsers.Load(v.Type()); !ok {
+		switch v.Kind() {
+		case reflect.Ptr:
+			elem := reflect.New(v.Type().Elem())
+			if value, err := setValue(elem.Elem(), values); err != nil {
+				return value, err
+			}
+			v.Set(elem)
+			return "", nil
+		case reflect.Slice:
+			if !reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+				slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+				for i, value := range values {
+					if err := setString(slice.Index(i), value); err != nil {
+						return value, err
+					}
+				}
+				v.Set(slice)
+				return "", nil
+			}
+		}
+	}
+
+	value := values[0]
+	return value, setString(v, value)
+}
+
+// setString parses the value and sets the field.
+func setString(v reflect.Value, value string) error {
+	if parser, ok := paramParsers.Load(v.Type()); ok {
+		x, err := parser.(func(string) (interface{}, error))(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(x))
+		return nil
+	}
+
+	switch dst := v.Addr().Interface().(type) {
+	case *time.Time, *time.Duration, encoding.TextUnmarshaler:
+		return parseParamValue(value, dst)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
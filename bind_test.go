@@ -0,0 +1,153 @@
+package bunrouter
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type Pagination struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
+
+type bindRequest struct {
+	Pagination
+
+	ID      uint64        `path:"id"`
+	Tenant  string        `header:"X-Tenant"`
+	Tags    []string      `query:"tag"`
+	Since   *time.Time    `query:"since"`
+	Timeout time.Duration `query:"timeout"`
+	Name    string        `json:"name" form:"name"`
+	Age     int           `json:"age" form:"age"`
+	Color   hexColor      `query:"color"`
+	private string        `query:"private"`
+}
+
+func bindRouter(dst *bindRequest) *Router {
+	router := New()
+	router.POST("/users/:id", func(w http.ResponseWriter, req Request) error {
+		*dst = bindRequest{Pagination: Pagination{Limit: 10}}
+		return Bind(req, dst)
+	})
+	return router
+}
+
+func TestBind(t *testing.T) {
+	var dst bindRequest
+	router := bindRouter(&dst)
+
+	t.Run("json", func(t *testing.T) {
+		body := strings.NewReader(`{"name": "joe", "age": 42}`)
+		req, _ := http.NewRequest("POST",
+			"/users/123?page=2&tag=a&tag=b&since=2024-01-02&timeout=1s&color=ff0000&private=x", body)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("X-Tenant", "acme")
+
+		w := httptest.NewRecorder()
+		require.NoError(t, router.ServeHTTPError(w, req))
+
+		since := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+		require.Equal(t, bindRequest{
+			Pagination: Pagination{Page: 2, Limit: 10},
+			ID:         123,
+			Tenant:     "acme",
+			Tags:       []string{"a", "b"},
+			Since:      &since,
+			Timeout:    time.Second,
+			Name:       "joe",
+			Age:        42,
+			Color:      hexColor{0xff, 0, 0},
+		}, dst)
+	})
+
+	t.Run("json can't set other sources", func(t *testing.T) {
+		body := strings.NewReader(`{"Tenant": "evil", "ID": 7, "Page": 9, "Limit": 99, "name": "joe"}`)
+		req, _ := http.NewRequest("POST", "/users/123", body)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		require.NoError(t, router.ServeHTTPError(w, req))
+		require.Equal(t, bindRequest{
+			Pagination: Pagination{Limit: 10},
+			ID:         123,
+			Name:       "joe",
+		}, dst)
+	})
+
+	t.Run("form", func(t *testing.T) {
+		body := strings.NewReader("name=joe&age=42")
+		req, _ := http.NewRequest("POST", "/users/123", body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+		require.NoError(t, router.ServeHTTPError(w, req))
+		require.Equal(t, "joe", dst.Name)
+		require.Equal(t, 42, dst.Age)
+	})
+
+	t.Run("multipart form", func(t *testing.T) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		require.NoError(t, mw.WriteField("name", "joe"))
+		require.NoError(t, mw.Close())
+
+		req, _ := http.NewRequest("POST", "/users/123", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+
+		w := httptest.NewRecorder()
+		require.NoError(t, router.ServeHTTPError(w, req))
+		require.Equal(t, "joe", dst.Name)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		body := strings.NewReader(`{"name": "joe", "age": "old"}`)
+		req, _ := http.NewRequest("POST", "/users/abc?page=x&limit=5", body)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		err := router.ServeHTTPError(w, req)
+
+		var bindErr *BindError
+		require.True(t, errors.As(err, &bindErr))
+		require.Equal(t, http.StatusBadRequest, bindErr.StatusCode())
+		require.Len(t, bindErr.Errors, 3)
+
+		require.Equal(t, "json", bindErr.Errors[0].Source)
+		require.Equal(t, "age", bindErr.Errors[0].Name)
+
+		require.Equal(t, "Page", bindErr.Errors[1].Field)
+		require.Equal(t, "query", bindErr.Errors[1].Source)
+		require.Equal(t, "x", bindErr.Errors[1].Value)
+
+		require.Equal(t, "ID", bindErr.Errors[2].Field)
+		require.Equal(t, "path", bindErr.Errors[2].Source)
+		require.Equal(t, "id", bindErr.Errors[2].Name)
+		require.Equal(t, "abc", bindErr.Errors[2].Value)
+
+		require.True(t, errors.Is(err, strconv.ErrSyntax))
+		require.Equal(t, 5, dst.Limit)
+		require.Contains(t, err.Error(), `query "page": strconv.ParseInt: parsing "x": invalid syntax`)
+	})
+
+	t.Run("invalid dst", func(t *testing.T) {
+		req := NewRequest(httptest.NewRequest("GET", "/", nil))
+
+		require.Error(t, Bind(req, dst))
+		require.Error(t, Bind(req, (*bindRequest)(nil)))
+
+		var unsupported struct {
+			Ch chan int `query:"ch"`
+		}
+		require.Error(t, Bind(req, &unsupported))
+	})
+}
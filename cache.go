@@ -0,0 +1,126 @@
+package bunrouter
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+)
+
+// routeSnapshot is a read-only copy of the routing tree used by lookups.
+// The exact-match table and the lookup cache are built on first use
+// and are discarded with the snapshot when routes change.
+type routeSnapshot struct {
+	root   *node
+	static atomic.Pointer[map[string]*node]
+	cache  atomic.Pointer[lookupCache]
+}
+
+func newRouteSnapshot(root *node) *routeSnapshot {
+	return &routeSnapshot{root: root}
+}
+
+// minStaticRoutes is the min number of static routes for the exact-match table.
+// Smaller trees are faster to walk than to hash the path.
+const minStaticRoutes = 64
+
+// staticNode returns the node for the fully static route, for example, "/api/v1/status".
+// Use it only if the tree has at least minStaticRoutes static routes.
+func (s *routeSnapshot) staticNode(path string) *node {
+	static := s.static.Load()
+	if static == nil {
+		m := make(map[string]*node)
+		s.root.collectStatic(m, nil)
+		static = &m
+		if !s.static.CompareAndSwap(nil, static) {
+			static = s.static.Load()
+		}
+	}
+	return (*static)[path]
+}
+
+// collectStatic adds the nodes that are reachable through static parts only.
+func (n *node) collectStatic(m map[string]*node, prefix []byte) {
+	prefix = append(prefix, n.part...)
+	if n.handlerMap != nil && !n.isWC {
+		m[string(prefix)] = n
+	}
+	for _, child := range n.nodes {
+		child.collectStatic(m, prefix)
+	}
+}
+
+// lookupCache returns the cache for the snapshot. The size must be positive.
+func (s *routeSnapshot) lookupCache(size int) *lookupCache {
+	if c := s.cache.Load(); c != nil {
+		return c
+	}
+	s.cache.CompareAndSwap(nil, newLookupCache(size))
+	return s.cache.Load()
+}
+
+//------------------------------------------------------------------------------
+
+// lookupCache is a fixed size set-associative cache of lookups that did not match
+// a route handler, for example, not found requests and redirects. Entries are
+// replaced without coordination, so the cache never blocks lookups.
+type lookupCache struct {
+	seed    maphash.Seed
+	mask    uint64
+	buckets []lookupBucket
+}
+
+// lookupWays is the number of entries in a bucket. Paths that hash to the same bucket
+// don't evict each other unless there are more of them.
+const lookupWays = 4
+
+type lookupBucket [lookupWays]atomic.Pointer[lookupEntry]
+
+type lookupEntry struct {
+	method  string
+	path    string
+	handler HandlerFunc
+}
+
+func newLookupCache(size int) *lookupCache {
+	n := 1
+	for n*lookupWays < size {
+		n <<= 1
+	}
+	return &lookupCache{
+		seed:    maphash.MakeSeed(),
+		mask:    uint64(n - 1),
+		buckets: make([]lookupBucket, n),
+	}
+}
+
+func (c *lookupCache) bucket(path string) (*lookupBucket, uint64) {
+	h := maphash.String(c.seed, path)
+	return &c.buckets[h&c.mask], h
+}
+
+func (c *lookupCache) Get(method, path string) HandlerFunc {
+	b, _ := c.bucket(path)
+	for i := range b {
+		if e := b[i].Load(); e != nil && e.path == path && e.method == method {
+			return e.handler
+		}
+	}
+	return nil
+}
+
+func (c *lookupCache) Set(method, path string, handler HandlerFunc) {
+	b, h := c.bucket(path)
+
+	// Take a free entry or replace the one picked by the hash.
+	slot := &b[(h>>32)%lookupWays]
+	for i := range b {
+		if b[i].Load() == nil {
+			slot = &b[i]
+			break
+		}
+	}
+	slot.Store(&lookupEntry{
+		method:  method,
+		path:    path,
+		handler: handler,
+	})
+}
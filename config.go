@@ -5,6 +5,18 @@ import "net/http"
 type config struct {
 	notFoundHandler         HandlerFunc
 	methodNotAllowedHandler HandlerFunc
+	autoOptions             bool
+	implicitHead            bool
+	constraints             map[string]func(string) bool
+	inlineParams            bool
+	caseInsensitive         bool
+	caseInsensitiveRedirect bool
+	lookupCacheSize         int
+	strictRoutes            bool
+	decodeParams            bool
+	errorHandler            ErrorHandler
+	recovery                *recoveryConfig
+	responseWriter          bool
 
 	group *Group
 }
@@ -36,6 +48,118 @@ func WithMethodNotAllowedHandler(handler HandlerFunc) Option {
 	})
 }
 
+// WithAutoOptions enables automatic replies to OPTIONS requests for routes
+// that don't have an explicit OPTIONS handler. The reply has the status code
+// http.StatusNoContent and the Allow header with the methods supported by the route.
+func WithAutoOptions() Option {
+	return option(func(c *config) {
+		c.autoOptions = true
+	})
+}
+
+// WithImplicitHead makes HEAD requests fall back to the GET handler of the route
+// when there is no explicit HEAD handler. The response body is discarded, but
+// the headers, including Content-Length, are sent as usual.
+func WithImplicitHead() Option {
+	return option(func(c *config) {
+		c.implicitHead = true
+	})
+}
+
+// WithParamConstraint registers a named param constraint that can be used in routes,
+// for example, "/users/:id<even>". Built-in constraints are int, uint, alpha, alnum,
+// hex, and uuid. Everything else in angle brackets is treated as a regular expression.
+func WithParamConstraint(name string, fn func(value string) bool) Option {
+	return option(func(c *config) {
+		if c.constraints == nil {
+			c.constraints = make(map[string]func(string) bool)
+		}
+		c.constraints[name] = fn
+	})
+}
+
+// WithInlineParams allows named params to share a path segment with static text,
+// for example, "/files/:name.json", "/v:version/users", or "/:from-:to". The param
+// name ends at the first char that is not a letter, a digit, or an underscore,
+// and a literal colon must be escaped with a backslash, for example, "/v1/items\\:batchGet".
+//
+// Without the option, a param takes the whole segment, so "/users/:user-id" has
+// the param "user-id" and the colon in "/v1/items:batchGet" is a static text.
+// With the option, routes like "/users/:user-id" panic because they are ambiguous.
+func WithInlineParams() Option {
+	return option(func(c *config) {
+		c.inlineParams = true
+	})
+}
+
+// WithCaseInsensitive makes static parts of routes match ignoring the ASCII case,
+// for example, "/API/Users/42" matches "/api/users/:id". Routes that match exactly
+// are preferred. Param values keep the original casing.
+func WithCaseInsensitive() Option {
+	return option(func(c *config) {
+		c.caseInsensitive = true
+	})
+}
+
+// WithCaseInsensitiveRedirect is like WithCaseInsensitive, but requests are
+// redirected to the path with the canonical casing, for example, from "/API/Users/42"
+// to "/api/users/42", unless the route uses RedirectServe.
+func WithCaseInsensitiveRedirect() Option {
+	return option(func(c *config) {
+		c.caseInsensitive = true
+		c.caseInsensitiveRedirect = true
+	})
+}
+
+// WithLookupCache enables a cache of the recent lookups that did not match a route,
+// for example, not found requests and redirects to the path with a trailing slash.
+// The cache holds at most size entries (rounded up to a power of two, at least 4) per host
+// and is cleared when routes change.
+func WithLookupCache(size int) Option {
+	return option(func(c *config) {
+		c.lookupCacheSize = size
+	})
+}
+
+// WithStrictRoutes makes Group.Handle panic when the route uses a different param name
+// at the same tree position as another route or when the route can't be reached
+// because of another route. Overlapping routes are allowed. See Router.Validate.
+func WithStrictRoutes() Option {
+	return option(func(c *config) {
+		c.strictRoutes = true
+	})
+}
+
+// WithDecodedParams makes the router match static parts and param constraints against
+// the percent-decoded request path and Params return decoded values, for example,
+// "hello world" instead of "hello%20world". Encoded slashes don't split path segments.
+// Use Params.RawByName to get the value as it appears in the request path.
+func WithDecodedParams() Option {
+	return option(func(c *config) {
+		c.decodeParams = true
+	})
+}
+
+// WithErrorHandler sets the handler for errors returned by the route handlers
+// and middlewares, including not found and method not allowed handlers.
+// Without it, Router.ServeHTTP discards the errors except for panics recovered
+// with WithRecovery. See DefaultErrorHandler.
+// It also enables WithResponseWriter.
+func WithErrorHandler(handler ErrorHandler) Option {
+	return option(func(c *config) {
+		c.errorHandler = handler
+	})
+}
+
+// WithResponseWriter makes the router wrap responses with ResponseWriter,
+// which middlewares can get with Request.ResponseWriter, for example,
+// to log the status code and the response size.
+func WithResponseWriter() Option {
+	return option(func(c *config) {
+		c.responseWriter = true
+	})
+}
+
 //------------------------------------------------------------------------------
 
 type GroupOption interface {
@@ -84,3 +208,89 @@ func WithHandler(fn HandlerFunc) GroupOption {
 		c.group.stack = append(c.group.stack, middleware)
 	})
 }
+
+// RedirectMode controls how requests are redirected to the canonical path of a route,
+// for example, from "/users/" to "/users" or from "/a//b" to "/a/b".
+type RedirectMode int
+
+const (
+	// RedirectPermanent replies with 301 Moved Permanently to GET and HEAD requests
+	// and with 308 Permanent Redirect to other requests so clients keep the method and body.
+	RedirectPermanent RedirectMode = iota
+	// RedirectTemporary replies with 302 Found to GET and HEAD requests
+	// and with 307 Temporary Redirect to other requests.
+	RedirectTemporary
+	// RedirectServe serves the route for the canonical path without redirecting.
+	RedirectServe
+)
+
+func (mode RedirectMode) statusCode(meth string) int {
+	get := meth == http.MethodGet || meth == http.MethodHead
+	switch {
+	case mode == RedirectTemporary && get:
+		return http.StatusFound
+	case mode == RedirectTemporary:
+		return http.StatusTemporaryRedirect
+	case get:
+		return http.StatusMovedPermanently
+	default:
+		return http.StatusPermanentRedirect
+	}
+}
+
+// redirectConfig is the redirect policy of the routes in a group.
+type redirectConfig struct {
+	noTrailingSlash bool
+	noCleanPath     bool
+	mode            RedirectMode
+}
+
+// WithRedirectTrailingSlash enables or disables redirects that add or remove
+// the trailing slash, for example, from "/users/" to "/users". Enabled by default.
+//
+// Like other redirect options, it applies to the routes registered afterwards
+// in the group and its sub-groups and is decided by the route the request is redirected to.
+func WithRedirectTrailingSlash(enabled bool) GroupOption {
+	return groupOption(func(c *config) {
+		c.group.redirect.noTrailingSlash = !enabled
+	})
+}
+
+// WithRedirectCleanPath enables or disables redirects to the cleaned path,
+// for example, from "/a//b/../c" to "/a/c". Enabled by default.
+func WithRedirectCleanPath(enabled bool) GroupOption {
+	return groupOption(func(c *config) {
+		c.group.redirect.noCleanPath = !enabled
+	})
+}
+
+// WithRedirectMode sets how requests are redirected. The default is RedirectPermanent.
+func WithRedirectMode(mode RedirectMode) GroupOption {
+	return groupOption(func(c *config) {
+		c.group.redirect.mode = mode
+	})
+}
+
+//------------------------------------------------------------------------------
+
+type routeConfig struct {
+	name string
+}
+
+// RouteOption configures a single route registered with Group.Handle.
+type RouteOption interface {
+	applyRoute(cfg *routeConfig)
+}
+
+type routeOption func(cfg *routeConfig)
+
+func (fn routeOption) applyRoute(cfg *routeConfig) {
+	fn(cfg)
+}
+
+// WithRouteName gives the route a name that can be used to generate URLs with Router.URL.
+func WithRouteName(name string) RouteOption {
+	return routeOption(func(cfg *routeConfig) {
+		cfg.name = name
+	})
+}
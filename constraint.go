@@ -0,0 +1,165 @@
+package bunrouter
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// builtinConstraints are the named param constraints that are always available,
+// for example, "/users/:id<int>".
+var builtinConstraints = map[string]func(string) bool{
+	"int":   isInt,
+	"uint":  isUint,
+	"alpha": isAlpha,
+	"alnum": isAlnum,
+	"hex":   isHex,
+	"uuid":  isUUID,
+}
+
+// paramConstraint restricts values that can be matched by a param node.
+// A constraint is either a name, for example, "int" or "uuid",
+// or a regular expression that must match the whole param value.
+type paramConstraint struct {
+	text string
+	fn   func(string) bool
+}
+
+func newParamConstraint(text string, constraints map[string]func(string) bool) *paramConstraint {
+	if fn, ok := constraints[text]; ok {
+		return &paramConstraint{text: text, fn: fn}
+	}
+	if fn, ok := builtinConstraints[text]; ok {
+		return &paramConstraint{text: text, fn: fn}
+	}
+
+	re, err := regexp.Compile("^(?:" + text + ")$")
+	if err != nil {
+		panic(fmt.Errorf("invalid param constraint %q: %w", text, err))
+	}
+	return &paramConstraint{text: text, fn: re.MatchString}
+}
+
+func (c *paramConstraint) spec() string {
+	if c == nil {
+		return ""
+	}
+	return c.text
+}
+
+func (c *paramConstraint) match(value string) bool {
+	if c == nil {
+		return true
+	}
+	return c.fn(value)
+}
+
+//------------------------------------------------------------------------------
+
+func isInt(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return isUint(s)
+}
+
+func isUint(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) && !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isHexDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID reports whether s looks like "6ba7b810-9dad-11d1-80b4-00c04fd430c8".
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHexDigit(s[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// swapCase returns the ASCII letter in the other case or the char as is.
+func swapCase(c byte) byte {
+	if isLetter(c) {
+		return c ^ 0x20
+	}
+	return c
+}
+
+// hasPrefixFold is like strings.HasPrefix, but it ignores the case of ASCII letters
+// so the prefix always has the same length in both strings.
+func hasPrefixFold(s, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if c1, c2 := s[i], prefix[i]; c1 != c2 && (!isLetter(c1) || c1^0x20 != c2) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,197 @@
+package bunrouter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrorHandler handles errors returned by the route handlers. See WithErrorHandler.
+// The request carries the params of the matched route, if any.
+type ErrorHandler func(w http.ResponseWriter, req Request, err error)
+
+// DefaultErrorHandler replies with the status code returned by StatusCode
+// and the error message. Problems are written as application/problem+json.
+// Errors that implement json.Marshaler, for example, *ValidationError,
+// are written as JSON. Recovered panics are replied with a generic
+// 500 Internal Server Error. Nothing is written when the handler has already
+// written the response headers.
+func DefaultErrorHandler(w http.ResponseWriter, req Request, err error) {
+	if HeaderWritten(w) {
+		return
+	}
+	writeError(w, err)
+}
+
+// writeError replies to the request with the error. Problems, including wrapped ones,
+// are written as problem details with the status code of the error, which may come
+// from the error mappings. The error itself, if it implements json.Marshaler,
+// and wrapped *ValidationError are written as JSON.
+func writeError(w http.ResponseWriter, err error) {
+	// The panic value may contain secrets, so it is never written.
+	if isPanic(err) {
+		code := http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+
+	code := StatusCode(err)
+
+	var problem *Problem
+	if errors.As(err, &problem) {
+		if problem.StatusCode() != code {
+			p := *problem
+			if p.Title == http.StatusText(p.StatusCode()) {
+				p.Title = ""
+			}
+			p.Status = code
+			problem = &p
+		}
+		writeProblem(w, problem)
+		return
+	}
+
+	// Other errors in the chain may contain secrets, so they are not written.
+	top := err
+	if mapped, ok := err.(*mappedError); ok {
+		top = mapped.err
+	}
+	m, ok := top.(json.Marshaler)
+	if !ok {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			m, ok = validationErr, true
+		}
+	}
+
+	if ok {
+		if b, jsonErr := m.MarshalJSON(); jsonErr == nil {
+			h := w.Header()
+			h.Del("Content-Length")
+			h.Set("Content-Type", "application/json; charset=utf-8")
+			h.Set("X-Content-Type-Options", "nosniff")
+			w.WriteHeader(code)
+			_, _ = w.Write(b)
+			return
+		}
+	}
+
+	http.Error(w, err.Error(), code)
+}
+
+// StatusCode returns the status code of the first HTTPError in the error chain
+// or http.StatusInternalServerError. Router.StatusCode also respects the error mappings.
+func StatusCode(err error) int {
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+//------------------------------------------------------------------------------
+
+// errorMapping maps matching errors to the status code.
+type errorMapping struct {
+	match  func(err error) bool
+	status int
+}
+
+// MapError makes the errors that match the target according to errors.Is
+// have the status code, for example, router.MapError(sql.ErrNoRows, http.StatusNotFound).
+// Mappings are checked in the order they were added, before HTTPError.
+// See Router.StatusCode.
+func (r *Router) MapError(target error, status int) {
+	if target == nil {
+		panic("bunrouter: nil error")
+	}
+	r.MapErrorFunc(func(err error) bool {
+		return errors.Is(err, target)
+	}, status)
+}
+
+// MapErrorFunc makes the errors for which the fn returns true have the status code.
+func (r *Router) MapErrorFunc(fn func(err error) bool, status int) {
+	if fn == nil {
+		panic("bunrouter: nil error matcher")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The slice is copied because error handling may be reading it.
+	var mappings []errorMapping
+	if p := r.errorMappings.Load(); p != nil {
+		mappings = *p
+	}
+	newMappings := make([]errorMapping, 0, len(mappings)+1)
+	newMappings = append(newMappings, mappings...)
+	newMappings = append(newMappings, errorMapping{match: fn, status: status})
+	r.errorMappings.Store(&newMappings)
+}
+
+// MapErrorAs makes the errors that have an error of the type T in the chain
+// according to errors.As have the status code, for example:
+//
+//	bunrouter.MapErrorAs[*json.SyntaxError](router, http.StatusBadRequest)
+func MapErrorAs[T error](r *Router, status int) {
+	r.MapErrorFunc(func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	}, status)
+}
+
+// StatusCode returns the status code of the first error mapping that matches the error,
+// except for recovered panics. Otherwise, it returns the status code of the first HTTPError in the error chain
+// or http.StatusInternalServerError. Custom error handlers and middlewares
+// can use it to reply with the same status code as the router.
+func (r *Router) StatusCode(err error) int {
+	if status, ok := r.mappedStatus(err); ok {
+		return status
+	}
+	return StatusCode(err)
+}
+
+func (r *Router) mappedStatus(err error) (int, bool) {
+	// Recovered panics are always internal errors.
+	if isPanic(err) {
+		return 0, false
+	}
+	if p := r.errorMappings.Load(); p != nil {
+		for _, mapping := range *p {
+			if mapping.match(err) {
+				return mapping.status, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// mapError wraps the error with the mapped status code so error handlers that
+// don't know about the router, for example, DefaultErrorHandler, can use it.
+func (r *Router) mapError(err error) error {
+	if status, ok := r.mappedStatus(err); ok {
+		return &mappedError{err: err, status: status}
+	}
+	return err
+}
+
+// mappedError is an error with the status code from the error mappings.
+type mappedError struct {
+	err    error
+	status int
+}
+
+var _ HTTPError = (*mappedError)(nil)
+
+func (e *mappedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *mappedError) Unwrap() error {
+	return e.err
+}
+
+func (e *mappedError) StatusCode() int {
+	return e.status
+}
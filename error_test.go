@@ -0,0 +1,120 @@
+package bunrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type quotaError struct{}
+
+func (quotaError) Error() string { return "quota exceeded" }
+
+func TestMapError(t *testing.T) {
+	errNotFound := errors.New("not found")
+	errPrivate := errors.New("private")
+
+	var handled error
+	router := New(WithErrorHandler(func(w http.ResponseWriter, req Request, err error) {
+		handled = err
+		DefaultErrorHandler(w, req, err)
+	}))
+	router.MapError(errNotFound, http.StatusNotFound)
+	router.MapErrorFunc(func(err error) bool {
+		return errors.Is(err, errPrivate)
+	}, http.StatusForbidden)
+	MapErrorAs[quotaError](router, http.StatusTooManyRequests)
+	router.MapError(context.DeadlineExceeded, http.StatusGatewayTimeout)
+
+	type Test struct {
+		err    error
+		status int
+	}
+	tests := []Test{
+		{fmt.Errorf("loading user: %w", errNotFound), http.StatusNotFound},
+		{errPrivate, http.StatusForbidden},
+		{fmt.Errorf("wrapped: %w", quotaError{}), http.StatusTooManyRequests},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{fmt.Errorf("param: %w", &ParamError{Name: "id", Err: ErrParamNotFound}), http.StatusBadRequest},
+		// Mappings are checked before HTTPError.
+		{&mappedError{err: errNotFound, status: http.StatusConflict}, http.StatusNotFound},
+		{errors.New("unknown"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.status, router.StatusCode(test.err), test.err.Error())
+
+		router.Remove("GET", "/")
+		router.GET("/", func(w http.ResponseWriter, req Request) error {
+			return test.err
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, test.status, w.Code, test.err.Error())
+		require.True(t, errors.Is(handled, test.err))
+		require.Equal(t, test.err.Error(), handled.Error())
+	}
+
+	require.Equal(t, http.StatusBadRequest, StatusCode(fmt.Errorf("wrapped: %w", BadRequest(""))))
+	require.Equal(t, http.StatusInternalServerError, StatusCode(errNotFound))
+}
+
+type secretError struct{}
+
+func (secretError) Error() string { return "secret" }
+
+func (secretError) MarshalJSON() ([]byte, error) {
+	return []byte(`{"dsn":"postgres://user:pw@db"}`), nil
+}
+
+func TestMapErrorResponse(t *testing.T) {
+	errNotFound := errors.New("not found")
+
+	router := New(WithErrorHandler(DefaultErrorHandler))
+	router.MapError(errNotFound, http.StatusNotFound)
+
+	var handlerErr error
+	router.GET("/", func(w http.ResponseWriter, req Request) error {
+		return handlerErr
+	})
+
+	t.Run("mapped problem", func(t *testing.T) {
+		handlerErr = BadRequest("x").Wrap(errNotFound)
+		require.Equal(t, http.StatusNotFound, router.StatusCode(handlerErr))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"x"}`,
+			w.Body.String())
+	})
+
+	t.Run("wrapped marshaler", func(t *testing.T) {
+		handlerErr = fmt.Errorf("wrap: %w", secretError{})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, "wrap: secret\n", w.Body.String())
+
+		handlerErr = secretError{}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, `{"dsn":"postgres://user:pw@db"}`, w.Body.String())
+	})
+
+	t.Run("wrapped validation error", func(t *testing.T) {
+		handlerErr = fmt.Errorf("wrap: %w", &ValidationError{})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	})
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -38,56 +40,31 @@ func indexHandler(w http.ResponseWriter, req bunrouter.Request) error {
 func errorHandler(next bunrouter.HandlerFunc) bunrouter.HandlerFunc {
 	return func(w http.ResponseWriter, req bunrouter.Request) error {
 		err := next(w, req)
-
-		switch err := err.(type) {
-		case nil:
-			// no error
-		case HTTPError:
-			w.WriteHeader(err.statusCode)
-			_ = bunrouter.JSON(w, err)
-		default:
-			httpErr := NewHTTPError(err)
-			w.WriteHeader(httpErr.statusCode)
-			_ = bunrouter.JSON(w, httpErr)
+		if err == nil {
+			return nil
 		}
 
+		problem := NewProblem(err)
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(problem.StatusCode())
+		_ = json.NewEncoder(w).Encode(problem)
+
 		return err
 	}
 }
 
-type HTTPError struct {
-	statusCode int
-
-	Code    string `json:"code"`
-	Message string `json:"message"`
-}
-
-func (e HTTPError) Error() string {
-	return e.Message
-}
-
-func NewHTTPError(err error) HTTPError {
-	switch err {
-	case io.EOF:
-		return HTTPError{
-			statusCode: http.StatusBadRequest,
-
-			Code:    "eof",
-			Message: "EOF reading HTTP request body",
-		}
-	case sql.ErrNoRows:
-		return HTTPError{
-			statusCode: http.StatusNotFound,
-
-			Code:    "not_found",
-			Message: "Page Not Found",
-		}
+// NewProblem converts the error to RFC 9457 problem details.
+func NewProblem(err error) *bunrouter.Problem {
+	var problem *bunrouter.Problem
+	if errors.As(err, &problem) {
+		return problem
 	}
 
-	return HTTPError{
-		statusCode: http.StatusInternalServerError,
-
-		Code:    "internal",
-		Message: "Internal server error",
+	switch {
+	case errors.Is(err, io.EOF):
+		return bunrouter.BadRequest("EOF reading HTTP request body").With("code", "eof")
+	case errors.Is(err, sql.ErrNoRows):
+		return bunrouter.NotFound("Page Not Found").With("code", "not_found")
 	}
+	return bunrouter.InternalServerError("Internal server error").With("code", "internal").Wrap(err)
 }
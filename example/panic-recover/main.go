@@ -1,11 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"runtime"
 
 	"github.com/uptrace/bunrouter"
 	"github.com/uptrace/bunrouter/extra/reqlog"
@@ -16,38 +16,25 @@ func main() {
 		bunrouter.Use(reqlog.NewMiddleware(
 			reqlog.FromEnv("BUNDEBUG"),
 		)),
+		// Set BUNREPANIC=1 to crash instead of recovering.
+		bunrouter.WithRecovery(bunrouter.Repanic(os.Getenv("BUNREPANIC") != "")),
+		bunrouter.WithErrorHandler(errorHandler),
 	)
 
 	router.GET("/", indexHandler)
 
-	handler := http.Handler(router)
-	handler = PanicHandler{Next: handler}
-
 	log.Println("listening on http://localhost:9999")
-	log.Println(http.ListenAndServe(":9999", handler))
+	log.Println(http.ListenAndServe(":9999", router))
 }
 
 func indexHandler(w http.ResponseWriter, req bunrouter.Request) error {
 	panic("oops")
 }
 
-type PanicHandler struct {
-	Next http.Handler
-}
-
-func (h PanicHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	defer func() {
-		if err := recover(); err != nil {
-			buf := make([]byte, 10<<10)
-			n := runtime.Stack(buf, false)
-			fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", err, buf[:n])
-
-			// Uncomment to exit instead of recovering.
-			// os.Exit(1)
-
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-		}
-	}()
-
-	h.Next.ServeHTTP(w, req)
+func errorHandler(w http.ResponseWriter, req bunrouter.Request, err error) {
+	var panicErr *bunrouter.PanicError
+	if errors.As(err, &panicErr) {
+		fmt.Fprintf(os.Stderr, "%s\n\n%s", panicErr, panicErr.Stack)
+	}
+	bunrouter.DefaultErrorHandler(w, req, err)
 }
@@ -71,11 +71,22 @@ func (m *middleware) Middleware(next bunrouter.HandlerFunc) bunrouter.HandlerFun
 	}
 
 	return func(w http.ResponseWriter, req bunrouter.Request) error {
-		rec := NewResponseWriter(w)
-
 		now := time.Now()
-		err := next(rec.Wrapped, req)
-		statusCode := rec.StatusCode()
+
+		var err error
+		var statusCode int
+		if rw := req.ResponseWriter(); rw != nil {
+			// Use the writer wrapped by the router, see bunrouter.WithResponseWriter.
+			err = next(w, req)
+			statusCode = rw.StatusCode()
+			if statusCode == 0 {
+				statusCode = http.StatusOK
+			}
+		} else {
+			rec := NewResponseWriter(w)
+			err = next(rec.Wrapped, req)
+			statusCode = rec.StatusCode()
+		}
 
 		if !m.verbose && statusCode >= 200 && statusCode < 300 && err == nil {
 			return nil
@@ -8,16 +8,22 @@ import (
 // Group is a group of routes and middlewares.
 type Group struct {
 	router *Router
+	table  *routeTable
 	path   string
 	stack  []MiddlewareFunc
+
+	redirect redirectConfig
 }
 
 // NewGroup adds a sub-group to this group.
 func (g *Group) NewGroup(path string, opts ...GroupOption) *Group {
 	group := &Group{
 		router: g.router,
+		table:  g.table,
 		path:   joinPath(g.path, path),
 		stack:  g.cloneStack(),
+
+		redirect: g.redirect,
 	}
 
 	cfg := &config{
@@ -46,72 +52,296 @@ func (g *Group) WithGroup(path string, fn func(g *Group)) {
 	fn(g.NewGroup(path))
 }
 
-func (g *Group) Handle(meth string, path string, handler HandlerFunc) {
+// Handle registers the handler for the method and path.
+// It is safe to register routes while the router is serving requests.
+func (g *Group) Handle(meth string, path string, handler HandlerFunc, opts ...RouteOption) {
+	g.router.mu.Lock()
+	defer g.router.mu.Unlock()
+
+	checkMethod(meth)
+	checkPath(path)
+	path = g.path + path
+	if path == "" {
+		panic("path can't be empty")
+	}
+
+	g.add(meth, path, handler, opts)
+	if g.table.strictRoutes {
+		g.checkStrict(meth, path)
+	}
+	g.table.publish()
+}
+
+// Replace is like Handle, but it replaces the handler of the route with the same
+// method and path instead of panicking. The route keeps its name unless
+// WithRouteName is given. Requests that are being served keep using the old handler.
+// If the new route can't be added, Replace panics and the old route is kept.
+func (g *Group) Replace(meth string, path string, handler HandlerFunc, opts ...RouteOption) {
 	g.router.mu.Lock()
 	defer g.router.mu.Unlock()
 
+	checkMethod(meth)
 	checkPath(path)
 	path = g.path + path
 	if path == "" {
 		panic("path can't be empty")
 	}
 
-	node, params := g.router.tree.addRoute(path)
+	old := g.remove(meth, path)
+	if old != nil && old.info.Name != "" {
+		opts = append([]RouteOption{WithRouteName(old.info.Name)}, opts...)
+	}
+
+	// Restore the old route if the new one can't be added.
+	var done bool
+	defer func() {
+		if !done && old != nil {
+			g.restore(old)
+		}
+	}()
+
+	g.add(meth, path, handler, opts)
+	if g.table.strictRoutes {
+		g.checkStrict(meth, path)
+	}
+	done = true
+	g.table.publish()
+}
+
+// Remove removes the route with the method and path registered in this group.
+// The path must be the same as the one passed to Handle. It reports whether
+// the route was removed. Requests that are being served are not affected.
+func (g *Group) Remove(meth string, path string) bool {
+	g.router.mu.Lock()
+	defer g.router.mu.Unlock()
+
+	checkPath(path)
+	path = g.path + path
+	if path == "" {
+		return false
+	}
+
+	if g.remove(meth, path) == nil {
+		return false
+	}
+	g.table.publish()
+	return true
+}
+
+func (g *Group) add(meth, path string, handler HandlerFunc, opts []RouteOption) {
+	var cfg routeConfig
+	for _, opt := range opts {
+		opt.applyRoute(&cfg)
+	}
+	if _, ok := g.router.names[cfg.name]; ok && cfg.name != "" {
+		panic(fmt.Errorf("route with name %q already exists", cfg.name))
+	}
+
+	source := callerSource()
+	h := &routeHandler{
+		fn:       g.wrap(handler),
+		route:    path,
+		redirect: g.redirect,
+		source:   source,
+	}
+	var head *routeHandler
+	if meth == http.MethodGet && g.table.implicitHead {
+		head = &routeHandler{
+			fn:       g.wrap(headHandler(handler)),
+			route:    path,
+			implicit: true,
+			redirect: g.redirect,
+			source:   source,
+		}
+	}
+
+	// Routes with optional segments are registered once for each combination of segments.
+	// If one of them can't be registered, the ones before it are removed.
+	routes := expandRoute(path)
+	undo := make([]func(), 0, len(routes))
+	defer func() {
+		if len(undo) < len(routes) {
+			for _, fn := range undo {
+				fn()
+			}
+		}
+	}()
+	for _, route := range routes {
+		undo = append(undo, g.handle(meth, route, h, head))
+	}
+
+	info := RouteInfo{
+		Name:   cfg.name,
+		Method: meth,
+		Path:   path,
+		Params: paramNames(routes[0], g.table.inlineParams),
+		Group:  g.path,
+		Host:   g.table.host,
+	}
+
+	if cfg.name != "" {
+		if g.router.names == nil {
+			g.router.names = make(map[string]*namedRoute)
+		}
+		g.router.names[cfg.name] = newNamedRoute(info, g.table.constraints, g.table.inlineParams)
+	}
+
+	g.router.routes = append(g.router.routes, info)
+}
+
+// removedRoute is a route removed from the working tree and the route registry.
+type removedRoute struct {
+	info    RouteInfo
+	index   int           // index in Router.routes
+	named   *namedRoute   // see WithRouteName
+	handler *routeHandler // handler of one of the route nodes
+	head    *routeHandler // implicit HEAD handler, if any
+}
+
+// remove removes the route from the working tree and the route registry.
+// It returns nil if the route was not found.
+func (g *Group) remove(meth, path string) *removedRoute {
+	var removed *removedRoute
+	for _, route := range expandRoute(path) {
+		node := g.table.tree.findNode(route, g.table.inlineParams)
+		if node == nil || node.handlerMap == nil {
+			continue
+		}
+
+		h := node.handlerMap.Get(meth)
+		if h == nil || h.implicit || h.route != path {
+			continue
+		}
+
+		removed = &removedRoute{handler: h}
+		if head := node.handlerMap.head; meth == http.MethodGet && head != nil && head.implicit {
+			removed.head = head
+		}
+		node.deleteHandler(meth)
+	}
+	if removed == nil {
+		return nil
+	}
+
+	routes := g.router.routes[:0:0]
+	for i, route := range g.router.routes {
+		if route.Method == meth && route.Path == path && route.Host == g.table.host {
+			removed.info = route
+			removed.index = i
+			continue
+		}
+		routes = append(routes, route)
+	}
+	g.router.routes = routes
 
+	if name := removed.info.Name; name != "" {
+		removed.named = g.router.names[name]
+		delete(g.router.names, name)
+	}
+
+	return removed
+}
+
+// restore adds the removed route back, see remove.
+func (g *Group) restore(removed *removedRoute) {
+	info := removed.info
+	for _, route := range expandRoute(info.Path) {
+		g.handle(info.Method, route, removed.handler, removed.head)
+	}
+
+	routes := make([]RouteInfo, 0, len(g.router.routes)+1)
+	routes = append(routes, g.router.routes[:removed.index]...)
+	routes = append(routes, info)
+	routes = append(routes, g.router.routes[removed.index:]...)
+	g.router.routes = routes
+
+	if removed.named != nil {
+		g.router.names[info.Name] = removed.named
+	}
+}
+
+// handle adds the handlers to the node of the route. It returns a func that removes them.
+func (g *Group) handle(meth, route string, h, head *routeHandler) (undo func()) {
+	node, params := g.table.tree.addRoute(route, g.table.constraints, g.table.inlineParams)
+	if node.route == "" {
+		node.route = h.route
+	}
+
+	// The implicit handler, for example, HEAD with WithImplicitHead, is replaced.
+	var implicit *routeHandler
 	if node.handlerMap != nil {
-		if h := node.handlerMap.Get(meth); h != nil {
-			if node.route == path {
+		implicit = node.handlerMap.Get(meth)
+		if implicit != nil && !implicit.implicit {
+			if node.route == h.route {
 				panic(fmt.Errorf("route %q already handles %s", node.route, meth))
 			}
-			panic(fmt.Errorf("routes %q and %q can't both handle %s", node.route, path, meth))
+			panic(fmt.Errorf("routes %q and %q can't both handle %s", node.route, h.route, meth))
 		}
 	}
-	node.setHandler(meth, &routeHandler{
-		fn:     g.wrap(handler),
-		params: params,
-	})
+	undo = func() {
+		node.deleteHandler(meth)
+		if implicit != nil {
+			node.setHandler(meth, implicit)
+		}
+	}
+
+	h = h.withParams(params)
+	node.setHandler(meth, h)
+
+	if head != nil && node.handlerMap.head == nil {
+		node.handlerMap.Set(http.MethodHead, head.withParams(params))
+	}
 
 	if node.handlerMap.notAllowed == nil {
 		node.handlerMap.notAllowed = &routeHandler{
-			fn:     g.wrap(g.router.methodNotAllowedHandler),
+			fn:     g.wrap(g.table.methodNotAllowedHandler),
 			params: params,
+			route:  h.route,
 		}
 	}
+	if g.table.autoOptions && node.handlerMap.autoOptions == nil {
+		node.handlerMap.setAutoOptions(&routeHandler{
+			fn:     g.wrap(optionsHandler),
+			params: params,
+			route:  h.route,
+		})
+	}
+	return undo
 }
 
 // Syntactic sugar for Handle("GET", path, handler)
-func (g *Group) GET(path string, handler HandlerFunc) {
-	g.Handle("GET", path, handler)
+func (g *Group) GET(path string, handler HandlerFunc, opts ...RouteOption) {
+	g.Handle("GET", path, handler, opts...)
 }
 
 // Syntactic sugar for Handle("POST", path, handler)
-func (g *Group) POST(path string, handler HandlerFunc) {
-	g.Handle("POST", path, handler)
+func (g *Group) POST(path string, handler HandlerFunc, opts ...RouteOption) {
+	g.Handle("POST", path, handler, opts...)
 }
 
 // Syntactic sugar for Handle("PUT", path, handler)
-func (g *Group) PUT(path string, handler HandlerFunc) {
-	g.Handle("PUT", path, handler)
+func (g *Group) PUT(path string, handler HandlerFunc, opts ...RouteOption) {
+	g.Handle("PUT", path, handler, opts...)
 }
 
 // Syntactic sugar for Handle("DELETE", path, handler)
-func (g *Group) DELETE(path string, handler HandlerFunc) {
-	g.Handle("DELETE", path, handler)
+func (g *Group) DELETE(path string, handler HandlerFunc, opts ...RouteOption) {
+	g.Handle("DELETE", path, handler, opts...)
 }
 
 // Syntactic sugar for Handle("PATCH", path, handler)
-func (g *Group) PATCH(path string, handler HandlerFunc) {
-	g.Handle("PATCH", path, handler)
+func (g *Group) PATCH(path string, handler HandlerFunc, opts ...RouteOption) {
+	g.Handle("PATCH", path, handler, opts...)
 }
 
 // Syntactic sugar for Handle("HEAD", path, handler)
-func (g *Group) HEAD(path string, handler HandlerFunc) {
-	g.Handle("HEAD", path, handler)
+func (g *Group) HEAD(path string, handler HandlerFunc, opts ...RouteOption) {
+	g.Handle("HEAD", path, handler, opts...)
 }
 
 // Syntactic sugar for Handle("OPTIONS", path, handler)
-func (g *Group) OPTIONS(path string, handler HandlerFunc) {
-	g.Handle("OPTIONS", path, handler)
+func (g *Group) OPTIONS(path string, handler HandlerFunc, opts ...RouteOption) {
+	g.Handle("OPTIONS", path, handler, opts...)
 }
 
 func (g *Group) wrap(handler HandlerFunc) HandlerFunc {
@@ -148,36 +378,38 @@ func (g CompatGroup) WithGroup(path string, fn func(g *CompatGroup)) {
 	fn(g.NewGroup(path))
 }
 
-func (g CompatGroup) Handle(method string, path string, handler http.HandlerFunc) {
-	g.group.Handle(method, path, HTTPHandlerFunc(handler))
+func (g CompatGroup) Handle(
+	method string, path string, handler http.HandlerFunc, opts ...RouteOption,
+) {
+	g.group.Handle(method, path, HTTPHandlerFunc(handler), opts...)
 }
 
-func (g CompatGroup) GET(path string, handler http.HandlerFunc) {
-	g.Handle(http.MethodGet, path, handler)
+func (g CompatGroup) GET(path string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodGet, path, handler, opts...)
 }
 
-func (g CompatGroup) POST(path string, handler http.HandlerFunc) {
-	g.Handle("POST", path, handler)
+func (g CompatGroup) POST(path string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle("POST", path, handler, opts...)
 }
 
-func (g CompatGroup) PUT(path string, handler http.HandlerFunc) {
-	g.Handle("PUT", path, handler)
+func (g CompatGroup) PUT(path string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle("PUT", path, handler, opts...)
 }
 
-func (g CompatGroup) DELETE(path string, handler http.HandlerFunc) {
-	g.Handle("DELETE", path, handler)
+func (g CompatGroup) DELETE(path string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle("DELETE", path, handler, opts...)
 }
 
-func (g CompatGroup) PATCH(path string, handler http.HandlerFunc) {
-	g.Handle("PATCH", path, handler)
+func (g CompatGroup) PATCH(path string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle("PATCH", path, handler, opts...)
 }
 
-func (g CompatGroup) HEAD(path string, handler http.HandlerFunc) {
-	g.Handle("HEAD", path, handler)
+func (g CompatGroup) HEAD(path string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle("HEAD", path, handler, opts...)
 }
 
-func (g CompatGroup) OPTIONS(path string, handler http.HandlerFunc) {
-	g.Handle("OPTIONS", path, handler)
+func (g CompatGroup) OPTIONS(path string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Handle("OPTIONS", path, handler, opts...)
 }
 
 //------------------------------------------------------------------------------
@@ -201,39 +433,41 @@ func (g VerboseGroup) WithGroup(path string, fn func(g *VerboseGroup)) {
 	fn(g.NewGroup(path))
 }
 
-func (g VerboseGroup) Handle(method string, path string, handler VerboseHandlerFunc) {
+func (g VerboseGroup) Handle(
+	method string, path string, handler VerboseHandlerFunc, opts ...RouteOption,
+) {
 	g.group.Handle(method, path, func(w http.ResponseWriter, req Request) error {
 		handler(w, req.Request, req.Params())
 		return nil
-	})
+	}, opts...)
 }
 
-func (g VerboseGroup) GET(path string, handler VerboseHandlerFunc) {
-	g.Handle(http.MethodGet, path, handler)
+func (g VerboseGroup) GET(path string, handler VerboseHandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodGet, path, handler, opts...)
 }
 
-func (g VerboseGroup) POST(path string, handler VerboseHandlerFunc) {
-	g.Handle("POST", path, handler)
+func (g VerboseGroup) POST(path string, handler VerboseHandlerFunc, opts ...RouteOption) {
+	g.Handle("POST", path, handler, opts...)
 }
 
-func (g VerboseGroup) PUT(path string, handler VerboseHandlerFunc) {
-	g.Handle("PUT", path, handler)
+func (g VerboseGroup) PUT(path string, handler VerboseHandlerFunc, opts ...RouteOption) {
+	g.Handle("PUT", path, handler, opts...)
 }
 
-func (g VerboseGroup) DELETE(path string, handler VerboseHandlerFunc) {
-	g.Handle("DELETE", path, handler)
+func (g VerboseGroup) DELETE(path string, handler VerboseHandlerFunc, opts ...RouteOption) {
+	g.Handle("DELETE", path, handler, opts...)
 }
 
-func (g VerboseGroup) PATCH(path string, handler VerboseHandlerFunc) {
-	g.Handle("PATCH", path, handler)
+func (g VerboseGroup) PATCH(path string, handler VerboseHandlerFunc, opts ...RouteOption) {
+	g.Handle("PATCH", path, handler, opts...)
 }
 
-func (g VerboseGroup) HEAD(path string, handler VerboseHandlerFunc) {
-	g.Handle("HEAD", path, handler)
+func (g VerboseGroup) HEAD(path string, handler VerboseHandlerFunc, opts ...RouteOption) {
+	g.Handle("HEAD", path, handler, opts...)
 }
 
-func (g VerboseGroup) OPTIONS(path string, handler VerboseHandlerFunc) {
-	g.Handle("OPTIONS", path, handler)
+func (g VerboseGroup) OPTIONS(path string, handler VerboseHandlerFunc, opts ...RouteOption) {
+	g.Handle("OPTIONS", path, handler, opts...)
 }
 
 //------------------------------------------------------------------------------
@@ -248,6 +482,12 @@ func joinPath(base, path string) string {
 	return path
 }
 
+func checkMethod(meth string) {
+	if !isValidMethod(meth) {
+		panic(fmt.Errorf("invalid HTTP method: %q", meth))
+	}
+}
+
 func checkPath(path string) {
 	// All non-empty paths must start with a slash
 	if len(path) > 0 && path[0] != '/' {
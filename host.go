@@ -0,0 +1,204 @@
+package bunrouter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// hostRoutes are the routes bound to a host pattern, for example,
+// "api.example.com" or ":tenant.example.com".
+type hostRoutes struct {
+	routeTable
+	labels []string       // static labels or ":" for params
+	params map[string]int // param name => label position
+}
+
+// Host returns a group of routes that only match requests for the host pattern.
+// Host labels that start with a colon are params, for example, ":tenant.example.com".
+// A param matches exactly one label, so "api.:domain" matches "api.example",
+// but not "api.example.com".
+// Host params are available through Request.Params alongside path params.
+//
+// Routes that are not bound to a host are used when no host pattern matches the request.
+// Options, for example, WithNotFoundHandler and WithMethodNotAllowedHandler,
+// only apply to the host.
+func (r *Router) Host(pattern string, opts ...Option) *Group {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	host := r.host(pattern)
+	if host == nil {
+		host = newHostRoutes(pattern)
+		host.config = r.config
+
+		var hosts []*hostRoutes
+		if p := r.hosts.Load(); p != nil {
+			hosts = *p
+		}
+
+		// Keep the hosts with more static labels first.
+		// The slice is copied because lookups may be reading it.
+		i := len(hosts)
+		for i > 0 && hosts[i-1].numStatic() < host.numStatic() {
+			i--
+		}
+		newHosts := make([]*hostRoutes, 0, len(hosts)+1)
+		newHosts = append(newHosts, hosts[:i]...)
+		newHosts = append(newHosts, host)
+		newHosts = append(newHosts, hosts[i:]...)
+		r.hosts.Store(&newHosts)
+	}
+
+	group := &Group{
+		router: r,
+		table:  &host.routeTable,
+		stack:  r.Group.cloneStack(),
+
+		redirect: r.Group.redirect,
+	}
+
+	host.config.group = group
+	for _, opt := range opts {
+		opt.apply(&host.config)
+	}
+
+	return group
+}
+
+func (r *Router) host(pattern string) *hostRoutes {
+	hosts := r.hosts.Load()
+	if hosts == nil {
+		return nil
+	}
+	for _, host := range *hosts {
+		if host.host == pattern {
+			return host
+		}
+	}
+	return nil
+}
+
+// findHost returns the host routes that match the request host
+// and the host name without a port.
+func (r *Router) findHost(req *http.Request) (*hostRoutes, string) {
+	hostname := req.Host
+	if hostname == "" && req.URL != nil {
+		hostname = req.URL.Host
+	}
+	hostname = stripPort(hostname)
+
+	for _, host := range *r.hosts.Load() {
+		if host.match(hostname) {
+			return host, hostname
+		}
+	}
+	return nil, ""
+}
+
+func newHostRoutes(pattern string) *hostRoutes {
+	if pattern == "" {
+		panic("host pattern can't be empty")
+	}
+
+	host := &hostRoutes{
+		routeTable: routeTable{
+			tree: node{
+				part: "/",
+			},
+			host: pattern,
+		},
+	}
+	host.publish()
+
+	var params []string
+	for _, label := range strings.Split(pattern, ".") {
+		if label == "" {
+			panic(fmt.Errorf("invalid host pattern: %q", pattern))
+		}
+		if label[0] == ':' {
+			params = append(params, label[1:])
+			host.labels = append(host.labels, ":")
+			continue
+		}
+		host.labels = append(host.labels, strings.ToLower(label))
+	}
+	if len(params) > 0 {
+		host.params = paramMap(pattern, params)
+	}
+
+	return host
+}
+
+func (h *hostRoutes) numStatic() int {
+	return len(h.labels) - len(h.params)
+}
+
+// match reports whether the hostname matches the pattern. Each label of the pattern,
+// including a param, matches exactly one label of the hostname.
+func (h *hostRoutes) match(hostname string) bool {
+	for i, label := range h.labels {
+		end := strings.IndexByte(hostname, '.')
+		if (end == -1) != (i == len(h.labels)-1) {
+			return false
+		}
+
+		var value string
+		if end == -1 {
+			value = hostname
+		} else {
+			value = hostname[:end]
+			hostname = hostname[end+1:]
+		}
+
+		if value == "" {
+			return false
+		}
+		if label != ":" && !strings.EqualFold(label, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// param returns the value of the named host param.
+func (h *hostRoutes) param(hostname, name string) (string, bool) {
+	index, ok := h.params[name]
+	if !ok {
+		return "", false
+	}
+
+	var paramIndex int
+	for _, label := range h.labels {
+		end := strings.IndexByte(hostname, '.')
+		if end == -1 {
+			end = len(hostname)
+		}
+
+		if label == ":" {
+			if paramIndex == index {
+				return hostname[:end], true
+			}
+			paramIndex++
+		}
+
+		if end == len(hostname) {
+			break
+		}
+		hostname = hostname[end+1:]
+	}
+	return "", false
+}
+
+// stripPort removes the port from the host, for example, "example.com:8080".
+func stripPort(host string) string {
+	i := strings.LastIndexByte(host, ':')
+	if i == -1 {
+		return host
+	}
+	// IPv6 address without a port, for example, "[::1]".
+	if strings.IndexByte(host[i:], ']') >= 0 {
+		return host
+	}
+	return host[:i]
+}
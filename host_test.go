@@ -0,0 +1,145 @@
+package bunrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostRouting(t *testing.T) {
+	var handler string
+	var params map[string]string
+
+	newHandler := func(name string) HandlerFunc {
+		return func(w http.ResponseWriter, req Request) error {
+			handler = name
+			params = req.Params().Map()
+			return nil
+		}
+	}
+
+	router := New()
+	router.GET("/users/:id", newHandler("default"))
+
+	api := router.Host("api.example.com")
+	api.GET("/users/:id", newHandler("api"))
+
+	tenant := router.Host(":tenant.example.com", WithNotFoundHandler(func(w http.ResponseWriter, req Request) error {
+		w.WriteHeader(http.StatusTeapot)
+		return nil
+	}))
+	tenant.NewGroup("/admin").GET("/users/:id", newHandler("tenant"))
+
+	type Test struct {
+		host    string
+		path    string
+		handler string
+		params  map[string]string
+	}
+
+	for _, test := range []Test{
+		{"example.com", "/users/1", "default", map[string]string{"id": "1"}},
+		{"API.example.com:8080", "/users/1", "api", map[string]string{"id": "1"}},
+		{"acme.example.com", "/admin/users/1", "tenant", map[string]string{
+			"id":     "1",
+			"tenant": "acme",
+		}},
+		{"a.b.example.com", "/users/1", "default", map[string]string{"id": "1"}},
+	} {
+		t.Run(test.host+test.path, func(t *testing.T) {
+			handler, params = "", nil
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+			req.Host = test.host
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, test.handler, handler)
+			require.Equal(t, test.params, params)
+		})
+	}
+
+	t.Run("host param", func(t *testing.T) {
+		var tenant string
+		var slice []Param
+		router.Host(":tenant.example.com").GET("/info", func(w http.ResponseWriter, req Request) error {
+			tenant = req.Param("tenant")
+			slice = req.Params().Slice()
+			return nil
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "http://acme.example.com/info", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "acme", tenant)
+		require.Equal(t, []Param{{Key: "tenant", Value: "acme"}}, slice)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "http://acme.example.com/users/1", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusTeapot, w.Code)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "http://api.example.com/admin/users/1", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "http://api.example.com/users/1", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		require.Equal(t, "GET", w.Header().Get("Allow"))
+	})
+}
+
+func TestHostLastParam(t *testing.T) {
+	var domain string
+	router := New()
+	router.GET("/", func(w http.ResponseWriter, req Request) error {
+		domain = "default"
+		return nil
+	})
+	router.Host("api.:domain").GET("/", func(w http.ResponseWriter, req Request) error {
+		domain = req.Param("domain")
+		return nil
+	})
+
+	for host, want := range map[string]string{
+		"api.foo":     "foo",
+		"api.foo.bar": "default",
+		"api.":        "default",
+	} {
+		domain = ""
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		require.Equal(t, want, domain, host)
+	}
+
+	host := newHostRoutes("api.:domain.:tld")
+	require.True(t, host.match("api.foo.com"))
+	require.False(t, host.match("api.foo.co.uk"))
+	require.False(t, host.match("api.foo"))
+
+	value, ok := host.param("api.foo.com", "tld")
+	require.True(t, ok)
+	require.Equal(t, "com", value)
+}
+
+func TestStripPort(t *testing.T) {
+	for host, want := range map[string]string{
+		"example.com":      "example.com",
+		"example.com:8080": "example.com",
+		"[::1]":            "[::1]",
+		"[::1]:8080":       "[::1]",
+	} {
+		require.Equal(t, want, stripPort(host))
+	}
+}
@@ -2,6 +2,7 @@ package bunrouter
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"sort"
 	"strings"
@@ -13,9 +14,12 @@ type node struct {
 
 	handlerMap *handlerMap
 
-	parent *node
-	colon  *node
-	isWC   bool
+	colons   []*node // param nodes, the ones with constraints go first
+	wildcard *node   // wildcard node followed by a static suffix, e.g. "/*path/blob"
+	isWC     bool    // terminal wildcard, e.g. "/*path"
+
+	constraint *paramConstraint // only for param nodes
+	mixed      bool             // param node followed by a static text in the same segment
 
 	nodes []*node
 	index struct {
@@ -23,37 +27,45 @@ type node struct {
 		minChar byte    // min char in the table
 		maxChar byte    // max char in the table
 	}
+
+	frozen    *node // read-only copy of the node or nil if the node was modified since
+	numStatic int   // number of fully static routes in the frozen subtree, see freeze
 }
 
-func (n *node) addRoute(route string) (*node, map[string]int) {
-	parts, params := splitRoute(route)
+func (n *node) addRoute(
+	route string, constraints map[string]func(string) bool, inline bool,
+) (*node, map[string]int) {
+	parts, params := splitRoute(route, inline)
 	currNode := n
 
-	for _, part := range parts {
-		currNode = currNode.addPart(part)
+	for i, part := range parts {
+		currNode.frozen = nil
+		switch {
+		case part[0] == ':':
+			currNode = currNode.addColon(part, constraints)
+		case part == "*" && i < len(parts)-1:
+			if currNode.wildcard == nil {
+				currNode.wildcard = &node{part: "*"}
+			}
+			currNode = currNode.wildcard
+		default:
+			currNode = currNode.addPart(part)
+		}
 	}
+	currNode.frozen = nil
 
-	if currNode.route == "" {
-		currNode.route = route
-	}
 	n.indexNodes()
 
 	return currNode, params
 }
 
 func (n *node) addPart(part string) *node {
+	n.frozen = nil
 	if part == "*" {
 		n.isWC = true
 		return n
 	}
 
-	if part == ":" {
-		if n.colon == nil {
-			n.colon = &node{part: ":"}
-		}
-		return n.colon
-	}
-
 	for childNodeIndex, childNode := range n.nodes {
 		if childNode.part[0] != part[0] {
 			continue
@@ -72,6 +84,7 @@ func (n *node) addPart(part string) *node {
 			// Create a node for the common prefix.
 
 			childNode.part = childNode.part[i:]
+			childNode.frozen = nil
 			newNode := &node{part: part[i:]}
 
 			n.nodes[childNodeIndex] = &node{
@@ -91,6 +104,7 @@ func (n *node) addPart(part string) *node {
 
 		case len(part) < len(childNode.part): // part is smaller
 			childNode.part = childNode.part[len(part):]
+			childNode.frozen = nil
 			newNode := &node{part: part}
 			newNode.nodes = []*node{childNode}
 			n.nodes[childNodeIndex] = newNode
@@ -106,87 +120,438 @@ func (n *node) addPart(part string) *node {
 	return node
 }
 
-func (n *node) findRoute(meth, path string) (*node, *routeHandler, int) {
+// addColon returns the param node for the part that looks like ":" or ":<constraint>".
+func (n *node) addColon(part string, constraints map[string]func(string) bool) *node {
+	n.frozen = nil
+	spec := part[1:]
+	for _, colon := range n.colons {
+		if colon.constraint.spec() == spec {
+			return colon
+		}
+	}
+
+	colon := &node{part: ":"}
+	if spec != "" {
+		colon.constraint = newParamConstraint(spec, constraints)
+	}
+
+	// Keep the param nodes without a constraint last so they are tried last.
+	i := len(n.colons)
+	if colon.constraint != nil {
+		for i > 0 && n.colons[i-1].constraint == nil {
+			i--
+		}
+	}
+	n.colons = append(n.colons, nil)
+	copy(n.colons[i+1:], n.colons[i:])
+	n.colons[i] = colon
+
+	return colon
+}
+
+// findNode returns the node for the route or nil if the route was not added.
+// Like addRoute, it marks the nodes on the route path as modified.
+func (n *node) findNode(route string, inline bool) *node {
+	parts, _ := splitRoute(route, inline)
+	return n.findParts(parts, func(n *node) {
+		n.frozen = nil
+	})
+}
+
+// findParts returns the node for the route parts or nil if the route was not added.
+// If visit is not nil, it is called for each node on the route path starting with n.
+func (n *node) findParts(parts []string, visit func(n *node)) *node {
+	if visit != nil {
+		visit(n)
+	}
+	currNode := n
+
+	for i, part := range parts {
+		switch {
+		case part[0] == ':':
+			spec := part[1:]
+			var found *node
+			for _, colon := range currNode.colons {
+				if colon.constraint.spec() == spec {
+					found = colon
+					break
+				}
+			}
+			currNode = found
+			if currNode != nil && visit != nil {
+				visit(currNode)
+			}
+		case part == "*" && i < len(parts)-1:
+			currNode = currNode.wildcard
+			if currNode != nil && visit != nil {
+				visit(currNode)
+			}
+		case part == "*":
+			if !currNode.isWC {
+				return nil
+			}
+		default:
+			currNode = currNode.findPart(part, visit)
+		}
+		if currNode == nil {
+			return nil
+		}
+	}
+
+	return currNode
+}
+
+func (n *node) findPart(part string, visit func(n *node)) *node {
+	for _, childNode := range n.nodes {
+		if childNode.part[0] != part[0] {
+			continue
+		}
+		if !strings.HasPrefix(part, childNode.part) {
+			return nil
+		}
+
+		if visit != nil {
+			visit(childNode)
+		}
+		if part = part[len(childNode.part):]; part == "" {
+			return childNode
+		}
+		return childNode.findPart(part, visit)
+	}
+	return nil
+}
+
+// freeze returns a read-only copy of the node that is used by lookups while
+// the node is being modified. Unmodified nodes reuse the copies made earlier.
+func (n *node) freeze() *node {
+	if n.frozen != nil {
+		return n.frozen
+	}
+
+	frozen := *n
+	if n.handlerMap != nil {
+		frozen.handlerMap = n.handlerMap.clone()
+	}
+	frozen.colons = freezeNodes(n.colons)
+	if n.wildcard != nil {
+		frozen.wildcard = n.wildcard.freeze()
+	}
+	frozen.nodes = freezeNodes(n.nodes)
+	frozen.index.table = append([]uint8(nil), n.index.table...)
+
+	frozen.numStatic = 0
+	if n.handlerMap != nil && !n.isWC {
+		frozen.numStatic++
+	}
+	for _, child := range frozen.nodes {
+		frozen.numStatic += child.numStatic
+	}
+
+	n.frozen = &frozen
+	return n.frozen
+}
+
+func freezeNodes(nodes []*node) []*node {
+	if len(nodes) == 0 {
+		return nil
+	}
+	frozen := make([]*node, len(nodes))
+	for i, node := range nodes {
+		frozen[i] = node.freeze()
+	}
+	return frozen
+}
+
+// maxParams is the max number of params in a route.
+const maxParams = 32
+
+// maxParamOffsets is the max number of param offsets recorded during a lookup.
+// Routes with more params capture values by repeating the lookup.
+const maxParamOffsets = 8
+
+// paramOffsets holds start and end offsets of param values in the path
+// in the reverse order, i.e. the last param goes first.
+type paramOffsets struct {
+	n   uint8
+	pos [maxParamOffsets][2]uint16
+}
+
+// value returns the value of the param with the index.
+func (o *paramOffsets) value(path string, paramIndex int) string {
+	pos := o.pos[int(o.n)-1-paramIndex]
+	return path[pos[0]:pos[1]]
+}
+
+// matcher holds the state of a single route lookup.
+type matcher struct {
+	meth   string
+	decode bool // see WithDecodedParams
+
+	// When target is set, the lookup searches for the target node
+	// instead of a handler for the method and captures param values.
+	target    *node
+	values    *[maxParams]string // param values in the reverse order
+	numValues int
+
+	// Otherwise, param offsets are recorded relative to the path of length pathLen
+	// if offsets is not nil.
+	pathLen int
+	offsets *paramOffsets
+
+	// When fold is set, static parts are matched ignoring ASCII case
+	// and the canonical path is built from the matched static parts.
+	fold      bool
+	canonical []byte
+}
+
+func (m *matcher) handler(n *node) *routeHandler {
+	if m.target != nil {
+		if n == m.target {
+			// Any handler will do. It isn't taken from the matcher,
+			// so the pointers in the matcher don't escape with it.
+			return n.handlerMap.notAllowed
+		}
+		return nil
+	}
+	return n.handlerMap.Get(m.meth)
+}
+
+// capture records the param value that starts the path.
+func (m *matcher) capture(path string, end int) {
+	if m.target != nil {
+		if m.numValues < len(m.values) {
+			m.values[m.numValues] = path[:end]
+			m.numValues++
+		}
+		return
+	}
+	if m.offsets == nil {
+		return
+	}
+
+	if m.offsets.n >= maxParamOffsets {
+		// Too many params. Mark offsets as incomplete.
+		m.offsets.n = math.MaxUint8
+		return
+	}
+	start := m.pathLen - len(path)
+	end += start
+	if end > math.MaxUint16 {
+		m.offsets.n = math.MaxUint8
+		return
+	}
+	m.offsets.pos[m.offsets.n] = [2]uint16{uint16(start), uint16(end)}
+	m.offsets.n++
+}
+
+// canonicalize replaces the start of the path with the static part of a node
+// in the canonical path. The path must be a suffix of the path being looked up.
+func (m *matcher) canonicalize(path, part string) {
+	if m.canonical != nil {
+		copy(m.canonical[len(m.canonical)-len(path):], part)
+	}
+}
+
+// match reports whether the param value satisfies the constraint.
+// With WithDecodedParams, the constraint is checked against the decoded value.
+func (m *matcher) match(c *paramConstraint, value string) bool {
+	if c != nil && m.decode {
+		value = unescapeParam(value)
+	}
+	return c.match(value)
+}
+
+// value returns the captured value of the param with the index.
+func (m *matcher) value(paramIndex int) (string, bool) {
+	if i := m.numValues - 1 - paramIndex; i >= 0 {
+		return m.values[i], true
+	}
+	return "", false
+}
+
+func (n *node) findRoute(m *matcher, path string) (*node, *routeHandler) {
 	if path == "" {
-		return nil, nil, 0
+		return nil, nil
 	}
+	m.pathLen = len(path)
 	path = path[1:] // strip leading "/"
 
 	if path == "" {
 		if n.handlerMap != nil {
-			return n, n.handlerMap.Get(meth), 0
+			handler := m.handler(n)
+			if handler != nil && n.isWC {
+				m.capture(path, 0)
+			}
+			return n, handler
 		}
-		return nil, nil, 0
+		return nil, nil
 	}
 
-	return n._findRoute(meth, path)
+	return n._findRoute(m, path)
+}
+
+// findRouteFold is like findRoute, but it ignores the case of static parts.
+// It also returns the path with static parts in the canonical case.
+func (n *node) findRouteFold(m *matcher, path string) (*node, *routeHandler, string) {
+	m.fold = true
+	m.canonical = []byte(path)
+	node, handler := n.findRoute(m, path)
+	if handler == nil {
+		return node, nil, path
+	}
+	return node, handler, string(m.canonical)
 }
 
-func (n *node) _findRoute(meth, path string) (*node, *routeHandler, int) {
+func (n *node) _findRoute(m *matcher, path string) (*node, *routeHandler) {
 	var found *node
 
-	if firstChar := path[0]; firstChar >= n.index.minChar && firstChar <= n.index.maxChar {
-		if i := n.index.table[firstChar-n.index.minChar]; i != 0 {
-			childNode := n.nodes[i-1]
+	// Try the static child with the same first char and then, when the case is ignored,
+	// the one with the first char in the other case.
+	for c := path[0]; ; {
+		if childNode := n.child(c); childNode != nil && (strings.HasPrefix(path, childNode.part) ||
+			m.fold && hasPrefixFold(path, childNode.part)) {
+			var node *node
+			var handler *routeHandler
+
+			if len(path) > len(childNode.part) {
+				node, handler = childNode._findRoute(m, path[len(childNode.part):])
+			} else if childNode.handlerMap != nil {
+				node, handler = childNode, m.handler(childNode)
+				if handler != nil && childNode.isWC {
+					m.capture(path[len(path):], 0)
+				}
+			}
 
-			if childNode.part == path {
-				if childNode.handlerMap != nil {
-					if handler := childNode.handlerMap.Get(meth); handler != nil {
-						return childNode, handler, 0
+			if handler != nil {
+				m.canonicalize(path, childNode.part)
+				return node, handler
+			}
+			if found == nil {
+				found = node
+			}
+		}
+
+		if !m.fold || c != path[0] || swapCase(c) == c {
+			break
+		}
+		c = swapCase(c)
+	}
+
+	if len(n.colons) > 0 && path[0] != '/' {
+		end := strings.IndexByte(path, '/')
+		if end == -1 {
+			end = len(path)
+		}
+
+		for _, colon := range n.colons {
+			// The param is followed by a static text in the same segment,
+			// for example, ":name.json". Try the shortest values first.
+			if colon.mixed {
+				for i := 1; i < end; i++ {
+					if !colon.hasChild(m, path[i]) || !m.match(colon.constraint, path[:i]) {
+						continue
 					}
-					found = childNode
-				}
-			} else {
-				partLen := len(childNode.part)
-				if strings.HasPrefix(path, childNode.part) {
-					node, handler, wildcardLen := childNode._findRoute(meth, path[partLen:])
+					node, handler := colon._findRoute(m, path[i:])
 					if handler != nil {
-						return node, handler, wildcardLen
+						m.capture(path, i)
+						return node, handler
 					}
-					if node != nil {
+					if node != nil && found == nil {
 						found = node
 					}
 				}
 			}
+
+			if colon.constraint != nil && !m.match(colon.constraint, path[:end]) {
+				continue
+			}
+
+			if end < len(path) {
+				node, handler := colon._findRoute(m, path[end:])
+				if handler != nil {
+					m.capture(path, end)
+					return node, handler
+				}
+				if node != nil && found == nil {
+					found = node
+				}
+			} else if colon.handlerMap != nil {
+				if handler := m.handler(colon); handler != nil {
+					m.capture(path, len(path))
+					return colon, handler
+				}
+				if found == nil {
+					found = colon
+				}
+			}
 		}
 	}
 
-	if n.colon != nil {
-		if i := strings.IndexByte(path, '/'); i > 0 {
-			node, handler, wildcardLen := n.colon._findRoute(meth, path[i:])
-			if handler != nil {
-				return node, handler, wildcardLen
+	// The wildcard is followed by a static suffix that starts with a slash.
+	// Try the shortest values first.
+	if n.wildcard != nil {
+		for i := 1; i < len(path); i++ {
+			if path[i] != '/' {
+				continue
 			}
-		} else if n.colon.handlerMap != nil {
-			if handler := n.colon.handlerMap.Get(meth); handler != nil {
-				return n.colon, handler, 0
+			node, handler := n.wildcard._findRoute(m, path[i:])
+			if handler != nil {
+				m.capture(path, i)
+				return node, handler
 			}
-			if found == nil {
-				found = n.colon
+			if node != nil && found == nil {
+				found = node
 			}
 		}
 	}
 
 	if n.isWC && n.handlerMap != nil {
-		if handler := n.handlerMap.Get(meth); handler != nil {
-			return n, handler, len(path)
+		if handler := m.handler(n); handler != nil {
+			m.capture(path, len(path))
+			return n, handler
 		}
 		if found == nil {
 			found = n
 		}
 	}
 
-	return found, nil, 0
+	return found, nil
+}
+
+func (n *node) hasChild(m *matcher, c byte) bool {
+	if n.child(c) != nil {
+		return true
+	}
+	return m.fold && n.child(swapCase(c)) != nil
+}
+
+// child returns the child node which part starts with the char.
+func (n *node) child(c byte) *node {
+	// The table covers the chars from minChar to maxChar.
+	if i := int(c) - int(n.index.minChar); uint(i) < uint(len(n.index.table)) {
+		if j := n.index.table[i]; j != 0 {
+			return n.nodes[j-1]
+		}
+	}
+	return nil
 }
 
 func (n *node) indexNodes() {
+	// Nodes that were not modified since they were frozen are already indexed.
+	if n.frozen != nil {
+		return
+	}
+
 	if len(n.nodes) > 0 {
 		n._indexNodes()
 	}
 
-	if n.colon != nil {
-		n.colon.parent = n
-		n.colon.indexNodes()
+	for _, colon := range n.colons {
+		colon.indexNodes()
+	}
+
+	if n.wildcard != nil {
+		n.wildcard.indexNodes()
 	}
 }
 
@@ -197,6 +562,7 @@ func (n *node) _indexNodes() {
 
 	n.index.minChar = n.nodes[0].part[0]
 	n.index.maxChar = n.nodes[len(n.nodes)-1].part[0]
+	n.mixed = n.part == ":" && (n.index.minChar != '/' || n.index.maxChar != '/')
 
 	// Reset index.
 	if size := int(n.index.maxChar - n.index.minChar + 1); len(n.index.table) != size {
@@ -209,7 +575,6 @@ func (n *node) _indexNodes() {
 
 	// Index nodes by the first char in a part.
 	for childNodeIndex, childNode := range n.nodes {
-		childNode.parent = n
 		childNode.indexNodes()
 
 		firstChar := childNode.part[0] - n.index.minChar
@@ -218,109 +583,217 @@ func (n *node) _indexNodes() {
 }
 
 func (n *node) setHandler(verb string, handler *routeHandler) {
+	n.frozen = nil
 	if n.handlerMap == nil {
 		n.handlerMap = newHandlerMap()
 	}
 	n.handlerMap.Set(verb, handler)
 }
 
+// deleteHandler deletes the handler for the verb and, for GET, the implicit HEAD handler.
+func (n *node) deleteHandler(verb string) {
+	n.frozen = nil
+	if n.handlerMap == nil {
+		return
+	}
+	n.handlerMap.Delete(verb)
+	if head := n.handlerMap.head; verb == http.MethodGet && head != nil && head.implicit {
+		n.handlerMap.Delete(http.MethodHead)
+	}
+	if n.handlerMap.isEmpty() {
+		n.handlerMap = nil
+		n.route = ""
+		n.isWC = false
+	}
+}
+
 //------------------------------------------------------------------------------
 
-type routeParser struct {
-	segments []string
-	i        int
+// splitRoute splits the route into parts for the tree nodes:
+//   - static parts are kept as is,
+//   - named params are replaced with ":" or ":constraint",
+//   - wildcards are replaced with "*".
+//
+// Named params take the whole segment unless inline is set. Inline params may share
+// a segment with static text, for example, "/files/:name.json" or "/v:version/users",
+// and a literal colon must be escaped with a backslash, for example, "/v1/items\\:batchGet".
+func splitRoute(route string, inline bool) (_ []string, _ map[string]int) {
+	if route == "" || route[0] != '/' {
+		panic(fmt.Errorf("invalid route: %q", route))
+	}
 
-	acc   []string
-	parts []string
-}
+	if route == "/" {
+		return []string{}, nil
+	}
 
-func (p *routeParser) valid() bool {
-	return p.i < len(p.segments)
-}
+	var parts []string
+	var params []string
+	var static []byte
 
-func (p *routeParser) next() string {
-	s := p.segments[p.i]
-	p.i++
-	return s
-}
+	finalizeStatic := func() {
+		if len(static) > 0 {
+			parts = append(parts, string(static))
+			static = static[:0]
+		}
+	}
 
-func (p *routeParser) accumulate(s string) {
-	p.acc = append(p.acc, s)
-}
+	for i := 1; i < len(route); {
+		switch c := route[i]; {
+		case inline && c == '\\' && i+1 < len(route) && route[i+1] == ':':
+			static = append(static, ':')
+			i += 2
+		case c == '*' && route[i-1] == '/':
+			end := strings.IndexByte(route[i:], '/')
+			if end == -1 {
+				end = len(route)
+			} else {
+				end += i
+			}
 
-func (p *routeParser) finalizePart(withSlash bool) {
-	if part := join(p.acc, withSlash); part != "" {
-		p.parts = append(p.parts, part)
-	}
-	p.acc = p.acc[:0]
+			finalizeStatic()
+			parts = append(parts, "*")
+			params = append(params, route[i+1:end])
+			i = end
+		case c == ':' && (inline || route[i-1] == '/'):
+			if len(static) == 0 && len(parts) > 0 && parts[len(parts)-1][0] == ':' {
+				panic(fmt.Errorf("params must be separated by a static text: %q", route))
+			}
 
-	if p.valid() {
-		p.acc = append(p.acc, "")
-	}
-}
+			name, spec, end := parseParam(route, i+1, inline)
 
-func join(ss []string, withSlash bool) string {
-	if len(ss) == 0 {
-		return ""
+			finalizeStatic()
+			if spec != "" {
+				parts = append(parts, ":"+spec)
+			} else {
+				parts = append(parts, ":")
+			}
+			params = append(params, name)
+			i = end
+		default:
+			static = append(static, c)
+			i++
+		}
 	}
-	s := strings.Join(ss, "/")
-	if withSlash {
-		return s + "/"
+
+	finalizeStatic()
+
+	if len(params) > 0 {
+		return parts, paramMap(route, params)
 	}
-	return s
+	return parts, nil
 }
 
-func splitRoute(route string) (_ []string, _ map[string]int) {
-	if route == "" || route[0] != '/' {
-		panic(fmt.Errorf("invalid route: %q", route))
+// expandRoute returns all routes that can be matched by the route with optional
+// segments, for example, "/reports/:year?" expands to "/reports/:year" and "/reports".
+// The route with all segments goes first.
+func expandRoute(route string) []string {
+	if !strings.Contains(route, "?") {
+		return []string{route}
 	}
 
-	if route == "/" {
-		return []string{}, nil
+	segments := strings.Split(route[1:], "/")
+	var optional []int
+	for i, segment := range segments {
+		if len(segment) > 1 && segment[len(segment)-1] == '?' {
+			segments[i] = segment[:len(segment)-1]
+			optional = append(optional, i)
+		}
 	}
-	route = route[1:] // trim first "/"
-
-	ss := strings.Split(route, "/")
-	if len(ss) == 0 {
-		panic(fmt.Errorf("invalid route: %q", route))
+	if len(optional) > 8 {
+		panic(fmt.Errorf("route can't have more than 8 optional segments: %q", route))
 	}
 
-	p := routeParser{
-		segments: ss,
-	}
-	var params []string
+	routes := make([]string, 0, 1<<len(optional))
+	seen := make(map[string]struct{}, 1<<len(optional))
+	skip := make([]bool, len(segments))
+	for mask := 0; mask < 1<<len(optional); mask++ {
+		for i, index := range optional {
+			skip[index] = mask&(1<<i) != 0
+		}
 
-	for p.valid() {
-		segment := p.next()
+		var b strings.Builder
+		for i, segment := range segments {
+			if !skip[i] {
+				b.WriteByte('/')
+				b.WriteString(segment)
+			}
+		}
+		if b.Len() == 0 {
+			b.WriteByte('/')
+		}
 
-		if segment == "" {
-			p.accumulate("")
-			continue
+		s := b.String()
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			routes = append(routes, s)
 		}
+	}
+	return routes
+}
 
-		switch firstChar := segment[0]; firstChar {
-		case ':':
-			p.finalizePart(true)
-			p.parts = append(p.parts, ":")
-			params = append(params, segment[1:])
-		case '*':
-			p.finalizePart(true)
-			p.parts = append(p.parts, "*")
-			params = append(params, segment[1:])
-		default:
-			p.accumulate(segment)
+// parseParam parses the param name and the optional constraint in angle brackets
+// starting at the index i. It returns the index of the first char after the param.
+//
+// Unless inline is set, the param takes the rest of the segment, so ":user-id" is
+// the param "user-id". Inline params end at the first char that can't be a part of
+// the name, so ":name.json" is the param "name" followed by ".json".
+func parseParam(route string, i int, inline bool) (name, spec string, _ int) {
+	start := i
+	if inline {
+		for i < len(route) && isParamNameChar(route[i]) {
+			i++
+		}
+		// ":user-id" is the param "user-id" without inline params,
+		// so don't silently give it another meaning.
+		if i+1 < len(route) && route[i] == '-' && isParamNameChar(route[i+1]) {
+			panic(fmt.Errorf("ambiguous param name followed by a dash, "+
+				"use an underscore or a constraint to end the name: %q", route))
+		}
+	} else {
+		for i < len(route) && route[i] != '/' && route[i] != '<' {
+			i++
 		}
 	}
+	name = route[start:i]
 
-	p.finalizePart(false)
+	if i < len(route) && route[i] == '<' {
+		end := strings.IndexByte(route[i:], '>')
+		if end <= 1 {
+			panic(fmt.Errorf("invalid param constraint: %q", route))
+		}
+		spec = route[i+1 : i+end]
+		if strings.IndexByte(spec, '/') >= 0 {
+			panic(fmt.Errorf("param constraint can't contain a slash: %q", route))
+		}
+		i += end + 1
+	}
 
-	if len(params) > 0 {
-		return p.parts, paramMap(route, params)
+	if !inline && i < len(route) && route[i] != '/' {
+		panic(fmt.Errorf("param must take the whole segment, "+
+			"use WithInlineParams to mix params with static text: %q", route))
 	}
-	return p.parts, nil
+
+	return name, spec, i
+}
+
+func isParamNameChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '_'
+}
+
+// paramNames returns the param names in the order they appear in the route.
+func paramNames(route string, inline bool) []string {
+	_, params := splitRoute(route, inline)
+	names := make([]string, len(params))
+	for name, i := range params {
+		names[i] = name
+	}
+	return names
 }
 
 func paramMap(route string, params []string) map[string]int {
+	if len(params) > maxParams {
+		panic(fmt.Errorf("route can't have more than %d params: %q", maxParams, route))
+	}
 	m := make(map[string]int, len(params))
 	for i, param := range params {
 		if param == "" {
@@ -341,12 +814,31 @@ type handlerMap struct {
 	head       *routeHandler
 	options    *routeHandler
 	patch      *routeHandler
+	other      []methodHandler // handlers for less common and custom methods
 	notAllowed *routeHandler
+
+	autoOptions *routeHandler // replies to OPTIONS when there is no explicit handler
+	allow       string        // value for the Allow header
+}
+
+type methodHandler struct {
+	method  string
+	handler *routeHandler
 }
 
 type routeHandler struct {
-	fn     HandlerFunc
-	params map[string]int // param name => param position
+	fn       HandlerFunc
+	params   map[string]int // param name => param position
+	route    string         // route pattern as it was registered
+	implicit bool           // HEAD handler derived from the GET handler
+	redirect redirectConfig // redirects to the route
+	source   string         // file:line where the route was registered
+}
+
+func (h *routeHandler) withParams(params map[string]int) *routeHandler {
+	clone := *h
+	clone.params = params
+	return &clone
 }
 
 func newHandlerMap() *handlerMap {
@@ -370,6 +862,11 @@ func (h *handlerMap) Get(meth string) *routeHandler {
 	case http.MethodPatch:
 		return h.patch
 	default:
+		for i := range h.other {
+			if h.other[i].method == meth {
+				return h.other[i].handler
+			}
+		}
 		return nil
 	}
 }
@@ -391,6 +888,103 @@ func (h *handlerMap) Set(meth string, handler *routeHandler) {
 	case http.MethodPatch:
 		h.patch = handler
 	default:
-		panic(fmt.Errorf("unknown HTTP method: %s", meth))
+		for i := range h.other {
+			if h.other[i].method == meth {
+				h.other[i].handler = handler
+				return
+			}
+		}
+		h.other = append(h.other, methodHandler{
+			method:  meth,
+			handler: handler,
+		})
+	}
+	h.updateAllow()
+}
+
+// Delete removes the handler for the method.
+func (h *handlerMap) Delete(meth string) {
+	if h.Get(meth) == nil {
+		return
+	}
+	for i := range h.other {
+		if h.other[i].method == meth {
+			h.other = append(h.other[:i:i], h.other[i+1:]...)
+			h.updateAllow()
+			return
+		}
+	}
+	h.Set(meth, nil)
+}
+
+// isEmpty reports whether there are no handlers for any method.
+func (h *handlerMap) isEmpty() bool {
+	return h.get == nil && h.post == nil && h.put == nil && h.delete == nil &&
+		h.head == nil && h.options == nil && h.patch == nil && len(h.other) == 0
+}
+
+func (h *handlerMap) clone() *handlerMap {
+	clone := *h
+	clone.other = append([]methodHandler(nil), h.other...)
+	return &clone
+}
+
+func (h *handlerMap) setAutoOptions(handler *routeHandler) {
+	h.autoOptions = handler
+	h.updateAllow()
+}
+
+// Methods returns the methods that have a handler in the canonical order.
+func (h *handlerMap) Methods() []string {
+	var methods []string
+	for _, m := range []struct {
+		method  string
+		handler *routeHandler
+	}{
+		{http.MethodGet, h.get},
+		{http.MethodHead, h.head},
+		{http.MethodPost, h.post},
+		{http.MethodPut, h.put},
+		{http.MethodPatch, h.patch},
+		{http.MethodDelete, h.delete},
+		{http.MethodOptions, h.options},
+	} {
+		if m.handler != nil {
+			methods = append(methods, m.method)
+		}
+	}
+	if h.options == nil && h.autoOptions != nil {
+		methods = append(methods, http.MethodOptions)
+	}
+	for _, m := range h.other {
+		if m.handler != nil {
+			methods = append(methods, m.method)
+		}
+	}
+	return methods
+}
+
+func (h *handlerMap) updateAllow() {
+	h.allow = strings.Join(h.Methods(), ", ")
+}
+
+// isValidMethod reports whether the method is a valid RFC 7230 token.
+func isValidMethod(meth string) bool {
+	if meth == "" {
+		return false
+	}
+	for i := 0; i < len(meth); i++ {
+		if !isTokenChar(meth[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isTokenChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
 	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
 }
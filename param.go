@@ -0,0 +1,189 @@
+package bunrouter
+
+import (
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrParamNotFound is wrapped by ParamError when the param is missing or empty.
+var ErrParamNotFound = errors.New("bunrouter: param not found")
+
+// ParamError is returned by the typed param accessors when the param is missing
+// or can't be parsed. It implements HTTPError with the status code 400 Bad Request
+// so handlers can return it as is.
+type ParamError struct {
+	Name  string // param name
+	Value string // param value
+	Err   error  // ErrParamNotFound or the parsing error
+}
+
+var _ HTTPError = (*ParamError)(nil)
+
+func (e *ParamError) Error() string {
+	if errors.Is(e.Err, ErrParamNotFound) {
+		return fmt.Sprintf("bunrouter: param '%s' not found", e.Name)
+	}
+	return fmt.Sprintf("bunrouter: can't parse param '%s': %s", e.Name, e.Err)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParamError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+//------------------------------------------------------------------------------
+
+// paramParsers holds parsers registered with RegisterParamParser by the type.
+var paramParsers sync.Map
+
+// RegisterParamParser registers the parser used by ParamAs for the type T.
+// Registered parsers take precedence over the built-in ones.
+// It is usually called from an init function.
+func RegisterParamParser[T any](fn func(value string) (T, error)) {
+	if fn == nil {
+		panic("bunrouter: nil param parser")
+	}
+	paramParsers.Store(reflect.TypeFor[T](), func(value string) (interface{}, error) {
+		return fn(value)
+	})
+}
+
+// ParamAs parses the named param as a value of the type T. Supported types are
+// string, bool, integers, floats, time.Time, time.Duration, types registered with
+// RegisterParamParser, and types that implement encoding.TextUnmarshaler.
+// The returned error is a *ParamError.
+func ParamAs[T any](ps Params, name string) (T, error) {
+	var dst T
+
+	value := ps.ByName(name)
+	if value == "" {
+		return dst, &ParamError{Name: name, Err: ErrParamNotFound}
+	}
+
+	if parser, ok := paramParsers.Load(reflect.TypeFor[T]()); ok {
+		v, err := parser.(func(string) (interface{}, error))(value)
+		if err != nil {
+			return dst, &ParamError{Name: name, Value: value, Err: err}
+		}
+		return v.(T), nil
+	}
+
+	if err := parseParamValue(value, &dst); err != nil {
+		var zero T
+		return zero, &ParamError{Name: name, Value: value, Err: err}
+	}
+	return dst, nil
+}
+
+func parseParamValue(value string, dst interface{}) error {
+	switch dst := dst.(type) {
+	case *string:
+		*dst = value
+	case *bool:
+		v, err := strconv.ParseBool(value)
+		*dst = v
+		return err
+	case *int:
+		v, err := strconv.Atoi(value)
+		*dst = v
+		return err
+	case *int8:
+		v, err := strconv.ParseInt(value, 10, 8)
+		*dst = int8(v)
+		return err
+	case *int16:
+		v, err := strconv.ParseInt(value, 10, 16)
+		*dst = int16(v)
+		return err
+	case *int32:
+		v, err := strconv.ParseInt(value, 10, 32)
+		*dst = int32(v)
+		return err
+	case *int64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		*dst = v
+		return err
+	case *uint:
+		v, err := strconv.ParseUint(value, 10, 0)
+		*dst = uint(v)
+		return err
+	case *uint8:
+		v, err := strconv.ParseUint(value, 10, 8)
+		*dst = uint8(v)
+		return err
+	case *uint16:
+		v, err := strconv.ParseUint(value, 10, 16)
+		*dst = uint16(v)
+		return err
+	case *uint32:
+		v, err := strconv.ParseUint(value, 10, 32)
+		*dst = uint32(v)
+		return err
+	case *uint64:
+		v, err := strconv.ParseUint(value, 10, 64)
+		*dst = v
+		return err
+	case *float32:
+		v, err := strconv.ParseFloat(value, 32)
+		*dst = float32(v)
+		return err
+	case *float64:
+		v, err := strconv.ParseFloat(value, 64)
+		*dst = v
+		return err
+	case *time.Time:
+		v, err := parseTime(value)
+		*dst = v
+		return err
+	case *time.Duration:
+		v, err := time.ParseDuration(value)
+		*dst = v
+		return err
+	case encoding.TextUnmarshaler:
+		return dst.UnmarshalText([]byte(value))
+	default:
+		return fmt.Errorf("unsupported type %T", dst)
+	}
+	return nil
+}
+
+// parseTime parses the time in the RFC 3339 format, for example, "2006-01-02T15:04:05Z",
+// or the date, for example, "2006-01-02".
+func parseTime(value string) (time.Time, error) {
+	if len(value) == len(time.DateOnly) {
+		return time.Parse(time.DateOnly, value)
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// parseUUID parses the UUID in the canonical form, for example,
+// "f47ac10b-58cc-4372-a567-0e02b2c3d479".
+func parseUUID(value string) ([16]byte, error) {
+	var uuid [16]byte
+	if !isUUID(value) {
+		return uuid, fmt.Errorf("invalid UUID %q", value)
+	}
+
+	var buf [32]byte
+	n := 0
+	for i := 0; i < len(value); i++ {
+		if value[i] != '-' {
+			buf[n] = value[i]
+			n++
+		}
+	}
+	if _, err := hex.Decode(uuid[:], buf[:]); err != nil {
+		return uuid, err
+	}
+	return uuid, nil
+}
@@ -0,0 +1,149 @@
+package bunrouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type hexColor [3]byte
+
+func (c *hexColor) UnmarshalText(b []byte) error {
+	if len(b) != 6 {
+		return fmt.Errorf("invalid color %q", b)
+	}
+	for i := range c {
+		n, err := strconv.ParseUint(string(b[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return err
+		}
+		c[i] = byte(n)
+	}
+	return nil
+}
+
+type upperString string
+
+func TestParamAccessors(t *testing.T) {
+	RegisterParamParser(func(value string) (upperString, error) {
+		return upperString(strings.ToUpper(value)), nil
+	})
+
+	var params Params
+	router := New()
+	router.GET("/:value", func(w http.ResponseWriter, req Request) error {
+		params = req.Params()
+		return nil
+	})
+
+	lookup := func(value string) Params {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/"+value, nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return params
+	}
+
+	ps := lookup("42")
+	n, err := ps.Int("value")
+	require.NoError(t, err)
+	require.Equal(t, 42, n)
+	u32, err := ps.Uint32("value")
+	require.NoError(t, err)
+	require.Equal(t, uint32(42), u32)
+	f, err := ps.Float64("value")
+	require.NoError(t, err)
+	require.Equal(t, 42.0, f)
+	i8, err := ParamAs[int8](ps, "value")
+	require.NoError(t, err)
+	require.Equal(t, int8(42), i8)
+
+	b, err := lookup("true").Bool("value")
+	require.NoError(t, err)
+	require.True(t, b)
+
+	uuid, err := lookup("f47ac10b-58cc-4372-a567-0e02b2c3d479").UUID("value")
+	require.NoError(t, err)
+	require.Equal(t, [16]byte{
+		0xf4, 0x7a, 0xc1, 0x0b, 0x58, 0xcc, 0x43, 0x72,
+		0xa5, 0x67, 0x0e, 0x02, 0xb2, 0xc3, 0xd4, 0x79,
+	}, uuid)
+
+	tm, err := lookup("2024-02-29").Time("value")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), tm)
+	tm, err = lookup("2024-02-29T10:30:00+02:00").Time("value")
+	require.NoError(t, err)
+	require.True(t, tm.Equal(time.Date(2024, 2, 29, 8, 30, 0, 0, time.UTC)))
+
+	d, err := lookup("1h30m").Duration("value")
+	require.NoError(t, err)
+	require.Equal(t, 90*time.Minute, d)
+
+	color, err := ParamAs[hexColor](lookup("ff8000"), "value")
+	require.NoError(t, err)
+	require.Equal(t, hexColor{0xff, 0x80, 0x00}, color)
+
+	s, err := ParamAs[upperString](lookup("abc"), "value")
+	require.NoError(t, err)
+	require.Equal(t, upperString("ABC"), s)
+}
+
+func TestParamError(t *testing.T) {
+	var params Params
+	router := New()
+	router.GET("/users/:id", func(w http.ResponseWriter, req Request) error {
+		params = req.Params()
+		_, err := req.Params().Int64("id")
+		return err
+	})
+	router.GET("/files/*path", func(w http.ResponseWriter, req Request) error {
+		params = req.Params()
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/abc", nil)
+	err := router.ServeHTTPError(w, req)
+
+	var paramErr *ParamError
+	require.True(t, errors.As(err, &paramErr))
+	require.Equal(t, "id", paramErr.Name)
+	require.Equal(t, "abc", paramErr.Value)
+	require.Equal(t, http.StatusBadRequest, paramErr.StatusCode())
+	require.True(t, errors.Is(err, strconv.ErrSyntax))
+	require.Equal(t, `bunrouter: can't parse param 'id': strconv.ParseInt: parsing "abc": invalid syntax`, err.Error())
+
+	_, err = params.Int32("id")
+	require.True(t, errors.As(err, &paramErr))
+	_, err = params.Uint32("id")
+	require.True(t, errors.As(err, &paramErr))
+	_, err = params.UUID("id")
+	require.True(t, errors.As(err, &paramErr))
+	_, err = ParamAs[struct{}](params, "id")
+	require.True(t, errors.As(err, &paramErr))
+
+	_, err = params.Int("missing")
+	require.True(t, errors.Is(err, ErrParamNotFound))
+	require.Equal(t, "bunrouter: param 'missing' not found", err.Error())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/files/", nil)
+	require.NoError(t, router.ServeHTTPError(w, req))
+	_, err = ParamAs[string](params, "path")
+	require.True(t, errors.Is(err, ErrParamNotFound))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/300", nil)
+	require.NoError(t, router.ServeHTTPError(w, req))
+	n, err := ParamAs[uint8](params, "id")
+	require.True(t, errors.Is(err, strconv.ErrRange))
+	require.Zero(t, n)
+}
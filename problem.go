@@ -0,0 +1,175 @@
+package bunrouter
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is an error that is rendered as RFC 9457 problem details
+// with the Content-Type application/problem+json, for example:
+//
+//	{
+//	  "type": "about:blank",
+//	  "title": "Not Found",
+//	  "status": 404,
+//	  "detail": "user 42 does not exist"
+//	}
+//
+// HandlerFunc.ServeHTTP and DefaultErrorHandler render wrapped problems as well,
+// for example, fmt.Errorf("loading user: %w", problem).
+type Problem struct {
+	Type     string // URI reference that identifies the problem type, "about:blank" by default
+	Title    string // short summary of the problem type, the status text by default
+	Status   int    // HTTP status code, http.StatusInternalServerError by default
+	Detail   string // explanation specific to this occurrence of the problem
+	Instance string // URI reference that identifies this occurrence of the problem
+
+	// Extensions are additional members of the problem details object.
+	// They can't override the standard members.
+	Extensions map[string]interface{}
+
+	// Err is the underlying error. It is not rendered.
+	Err error
+}
+
+var (
+	_ HTTPError      = (*Problem)(nil)
+	_ json.Marshaler = (*Problem)(nil)
+)
+
+// NewProblem returns a problem with the status code and the detail.
+// The title is the status text, for example, "Not Found".
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// BadRequest returns a problem with the status code 400 Bad Request.
+func BadRequest(detail string) *Problem {
+	return NewProblem(http.StatusBadRequest, detail)
+}
+
+// Unauthorized returns a problem with the status code 401 Unauthorized.
+func Unauthorized(detail string) *Problem {
+	return NewProblem(http.StatusUnauthorized, detail)
+}
+
+// Forbidden returns a problem with the status code 403 Forbidden.
+func Forbidden(detail string) *Problem {
+	return NewProblem(http.StatusForbidden, detail)
+}
+
+// NotFound returns a problem with the status code 404 Not Found.
+func NotFound(detail string) *Problem {
+	return NewProblem(http.StatusNotFound, detail)
+}
+
+// Conflict returns a problem with the status code 409 Conflict.
+func Conflict(detail string) *Problem {
+	return NewProblem(http.StatusConflict, detail)
+}
+
+// UnprocessableEntity returns a problem with the status code 422 Unprocessable Entity.
+func UnprocessableEntity(detail string) *Problem {
+	return NewProblem(http.StatusUnprocessableEntity, detail)
+}
+
+// TooManyRequests returns a problem with the status code 429 Too Many Requests.
+func TooManyRequests(detail string) *Problem {
+	return NewProblem(http.StatusTooManyRequests, detail)
+}
+
+// InternalServerError returns a problem with the status code 500 Internal Server Error.
+func InternalServerError(detail string) *Problem {
+	return NewProblem(http.StatusInternalServerError, detail)
+}
+
+// With sets the extension member and returns the problem.
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Wrap sets the underlying error and returns the problem.
+func (p *Problem) Wrap(err error) *Problem {
+	p.Err = err
+	return p
+}
+
+func (p *Problem) Error() string {
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.StatusCode())
+	}
+	if p.Detail == "" {
+		return title
+	}
+	return title + ": " + p.Detail
+}
+
+func (p *Problem) Unwrap() error {
+	return p.Err
+}
+
+func (p *Problem) StatusCode() int {
+	if p.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return p.Status
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+
+	typ := p.Type
+	if typ == "" {
+		typ = "about:blank"
+	}
+	m["type"] = typ
+
+	status := p.StatusCode()
+	if p.Title != "" {
+		m["title"] = p.Title
+	} else {
+		m["title"] = http.StatusText(status)
+	}
+	m["status"] = status
+
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	} else {
+		delete(m, "detail")
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	} else {
+		delete(m, "instance")
+	}
+
+	return json.Marshal(m)
+}
+
+// writeProblem replies to the request with the problem details.
+func writeProblem(w http.ResponseWriter, p *Problem) {
+	b, err := p.MarshalJSON()
+	if err != nil {
+		http.Error(w, p.Error(), p.StatusCode())
+		return
+	}
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.StatusCode())
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,70 @@
+package bunrouter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblem(t *testing.T) {
+	cause := errors.New("no rows")
+	problem := NotFound("user 42 does not exist").With("user_id", 42).Wrap(cause)
+	problem.Instance = "/users/42"
+
+	require.Equal(t, http.StatusNotFound, problem.StatusCode())
+	require.Equal(t, "Not Found: user 42 does not exist", problem.Error())
+	require.True(t, errors.Is(problem, cause))
+
+	b, err := json.Marshal(problem)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Not Found",
+		"status": 404,
+		"detail": "user 42 does not exist",
+		"instance": "/users/42",
+		"user_id": 42
+	}`, string(b))
+
+	b, err = json.Marshal(&Problem{Extensions: map[string]interface{}{"status": 200, "detail": "x"}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type": "about:blank", "title": "Internal Server Error", "status": 500}`, string(b))
+}
+
+func TestProblemResponse(t *testing.T) {
+	handler := func(w http.ResponseWriter, req Request) error {
+		return fmt.Errorf("loading user: %w", Conflict("email is taken").With("field", "email"))
+	}
+
+	check := func(t *testing.T, w *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusConflict, w.Code)
+		require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		require.JSONEq(t, `{
+			"type": "about:blank",
+			"title": "Conflict",
+			"status": 409,
+			"detail": "email is taken",
+			"field": "email"
+		}`, w.Body.String())
+	}
+
+	t.Run("HandlerFunc", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HandlerFunc(handler).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		check(t, w)
+	})
+
+	t.Run("DefaultErrorHandler", func(t *testing.T) {
+		router := New(WithErrorHandler(DefaultErrorHandler))
+		router.GET("/", handler)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		check(t, w)
+	})
+}
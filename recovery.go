@@ -0,0 +1,96 @@
+package bunrouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoveryOption configures WithRecovery.
+type RecoveryOption func(c *recoveryConfig)
+
+type recoveryConfig struct {
+	repanic bool
+}
+
+// Repanic makes the router panic again with the recovered value instead of
+// returning PanicError, for example, to crash early in development.
+func Repanic(enabled bool) RecoveryOption {
+	return func(c *recoveryConfig) {
+		c.repanic = enabled
+	}
+}
+
+// WithRecovery makes the router recover panics in the route handlers and middlewares
+// and return them as *PanicError through the normal error path, for example,
+// to the handler set with WithErrorHandler. Without the error handler, Router.ServeHTTP
+// logs the panic with the stack trace and replies with 500 Internal Server Error
+// unless the response is already started.
+//
+// Panics with http.ErrAbortHandler are not recovered so net/http can abort the response.
+func WithRecovery(opts ...RecoveryOption) Option {
+	return option(func(c *config) {
+		conf := new(recoveryConfig)
+		for _, opt := range opts {
+			opt(conf)
+		}
+		c.recovery = conf
+	})
+}
+
+// serve is like calling the handler, but it converts panics to PanicError.
+func (c *recoveryConfig) serve(handler HandlerFunc, w http.ResponseWriter, req Request) (err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		if v == http.ErrAbortHandler || c.repanic {
+			panic(v)
+		}
+		err = &PanicError{
+			Value:  v,
+			Stack:  debug.Stack(),
+			Method: req.Method,
+			Route:  req.Route(),
+		}
+	}()
+	return handler(w, req)
+}
+
+// PanicError is returned by the router with WithRecovery when a handler panics.
+// It implements HTTPError with the status code 500 Internal Server Error.
+// Error mappings don't apply to it, and DefaultErrorHandler doesn't write
+// the panic value to the response.
+type PanicError struct {
+	Value  interface{} // recovered value
+	Stack  []byte      // stack trace of the goroutine that panicked
+	Method string      // request method
+	Route  string      // matched route, for example, "/users/:id"
+}
+
+var _ HTTPError = (*PanicError)(nil)
+
+func (e *PanicError) Error() string {
+	if e.Route == "" {
+		return fmt.Sprintf("bunrouter: panic: %v", e.Value)
+	}
+	return fmt.Sprintf("bunrouter: panic in %s %s: %v", e.Method, e.Route, e.Value)
+}
+
+// Unwrap returns the recovered value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+func (e *PanicError) StatusCode() int {
+	return http.StatusInternalServerError
+}
+
+// isPanic reports whether the error chain has a recovered panic.
+func isPanic(err error) bool {
+	var panicErr *PanicError
+	return errors.As(err, &panicErr)
+}
@@ -0,0 +1,122 @@
+package bunrouter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecovery(t *testing.T) {
+	var handled error
+	router := New(
+		WithRecovery(),
+		WithErrorHandler(func(w http.ResponseWriter, req Request, err error) {
+			handled = err
+			DefaultErrorHandler(w, req, err)
+		}),
+	)
+	router.GET("/users/:id", func(w http.ResponseWriter, req Request) error {
+		panic("oops")
+	})
+	router.GET("/eof", func(w http.ResponseWriter, req Request) error {
+		panic(io.EOF)
+	})
+	router.GET("/abort", func(w http.ResponseWriter, req Request) error {
+		panic(http.ErrAbortHandler)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/users/1", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var panicErr *PanicError
+	require.True(t, errors.As(handled, &panicErr))
+	require.Equal(t, "oops", panicErr.Value)
+	require.Equal(t, "/users/:id", panicErr.Route)
+	require.Equal(t, "bunrouter: panic in GET /users/:id: oops", panicErr.Error())
+	require.Contains(t, string(panicErr.Stack), "recovery_test.go")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/eof", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.True(t, errors.Is(handled, io.EOF))
+
+	err := router.ServeHTTPError(httptest.NewRecorder(), httptest.NewRequest("GET", "/eof", nil))
+	require.True(t, errors.As(err, &panicErr))
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+	})
+}
+
+func TestRecoveryHidesPanic(t *testing.T) {
+	errNotFound := errors.New("not found")
+
+	router := New(WithRecovery(), WithErrorHandler(DefaultErrorHandler))
+	router.MapError(errNotFound, http.StatusNotFound)
+	router.GET("/secret", func(w http.ResponseWriter, req Request) error {
+		panic("secret token 123")
+	})
+	router.GET("/mapped", func(w http.ResponseWriter, req Request) error {
+		panic(errNotFound)
+	})
+	router.GET("/problem", func(w http.ResponseWriter, req Request) error {
+		panic(NotFound("secret"))
+	})
+
+	for _, path := range []string{"/secret", "/mapped", "/problem"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code, path)
+		require.Equal(t, "Internal Server Error\n", w.Body.String(), path)
+	}
+
+	err := router.ServeHTTPError(httptest.NewRecorder(), httptest.NewRequest("GET", "/mapped", nil))
+	require.True(t, errors.Is(err, errNotFound))
+	require.Equal(t, http.StatusInternalServerError, router.StatusCode(err))
+}
+
+func TestRecoveryRepanic(t *testing.T) {
+	router := New(WithRecovery(Repanic(true)))
+	router.GET("/", func(w http.ResponseWriter, req Request) error {
+		panic("oops")
+	})
+
+	require.PanicsWithValue(t, "oops", func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
+
+func TestRecoveryWithoutErrorHandler(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	router := New(WithRecovery())
+	router.GET("/users/:id", func(w http.ResponseWriter, req Request) error {
+		panic("oops")
+	})
+	router.GET("/started", func(w http.ResponseWriter, req Request) error {
+		w.WriteHeader(http.StatusAccepted)
+		panic("oops")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/users/1", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, "Internal Server Error\n", w.Body.String())
+	require.Contains(t, logs.String(), "bunrouter: panic in GET /users/:id: oops")
+	require.Contains(t, logs.String(), "recovery_test.go")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/started", nil))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Empty(t, w.Body.String())
+}
@@ -5,8 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
+	"time"
 )
 
 type routeCtxKey struct{}
@@ -54,6 +55,9 @@ func HTTPHandlerFunc(handler http.HandlerFunc) HandlerFunc {
 
 		defer func() {
 			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
 				var ok bool
 				err, ok = v.(error)
 				if !ok {
@@ -86,11 +90,7 @@ func (h HandlerFunc) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if err := h(w, NewRequest(req)); err != nil {
-		code := http.StatusInternalServerError
-		if httpErr, ok := err.(HTTPError); ok {
-			code = httpErr.StatusCode()
-		}
-		http.Error(w, err.Error(), code)
+		writeError(w, err)
 	}
 }
 
@@ -109,6 +109,7 @@ type MiddlewareFunc func(next HandlerFunc) HandlerFunc
 type Request struct {
 	*http.Request
 	params Params
+	rw     *ResponseWriter
 }
 
 // NewRequest creates a new Request instance from an http.Request.
@@ -122,16 +123,6 @@ func NewRequest(req *http.Request) Request {
 	}
 }
 
-func newRequestParams(req *http.Request, params Params) Request {
-	if req == nil {
-		req = &http.Request{}
-	}
-	return Request{
-		Request: req,
-		params:  params,
-	}
-}
-
 // WithContext returns a new Request with the provided context.
 func (req Request) WithContext(ctx context.Context) Request {
 	if ctx == nil {
@@ -140,6 +131,7 @@ func (req Request) WithContext(ctx context.Context) Request {
 	return Request{
 		Request: req.Request.WithContext(ctx),
 		params:  req.params,
+		rw:      req.rw,
 	}
 }
 
@@ -148,6 +140,12 @@ func (req Request) Params() Params {
 	return req.params
 }
 
+// ResponseWriter returns the response writer wrapped by the router with
+// WithResponseWriter or WithErrorHandler. Otherwise, it returns nil.
+func (req Request) ResponseWriter() *ResponseWriter {
+	return req.rw
+}
+
 // Param returns the value of the named parameter or empty string if not found.
 func (req Request) Param(key string) string {
 	return req.Params().ByName(key)
@@ -158,14 +156,25 @@ func (req Request) Route() string {
 	return req.Params().Route()
 }
 
+// AllowedMethods returns the methods supported by the matched route.
+// It is useful in custom handlers for 405 Method Not Allowed responses.
+func (req Request) AllowedMethods() []string {
+	return req.Params().AllowedMethods()
+}
+
 //------------------------------------------------------------------------------
 
 // Params holds route parameters and route information.
 type Params struct {
-	path        string
-	node        *node
-	handler     *routeHandler
-	wildcardLen uint16
+	path    string
+	tree    *node
+	node    *node
+	handler *routeHandler
+	offsets paramOffsets // recorded during the lookup
+	raw     string       // escaped request path if params must be decoded, see decodePath
+
+	host     *hostRoutes
+	hostname string
 }
 
 // IsZero returns true if Params has no associated route node.
@@ -175,87 +184,202 @@ func (ps Params) IsZero() bool {
 
 // Route returns the route pattern that matched the request.
 func (ps Params) Route() string {
+	if ps.handler != nil && ps.handler.route != "" {
+		return ps.handler.route
+	}
 	if ps.node != nil {
 		return ps.node.route
 	}
 	return ""
 }
 
+// AllowedMethods returns the methods supported by the matched route.
+func (ps Params) AllowedMethods() []string {
+	if ps.node == nil || ps.node.handlerMap == nil {
+		return nil
+	}
+	return ps.node.handlerMap.Methods()
+}
+
 // Get returns the value of the named parameter and whether it was found.
 func (ps Params) Get(name string) (string, bool) {
-	if ps.node == nil || ps.handler == nil {
-		return "", false
+	return ps.get(name, ps.raw != "")
+}
+
+// RawByName is like ByName, but it returns the value of the param as it appears
+// in the request path, for example, "hello%20world". See WithDecodedParams.
+func (ps Params) RawByName(name string) string {
+	s, _ := ps.get(name, false)
+	return s
+}
+
+func (ps *Params) get(name string, decode bool) (string, bool) {
+	if ps.node != nil && ps.handler != nil {
+		if i, ok := ps.handler.params[name]; ok {
+			if !decode && ps.raw != "" {
+				if value, ok := ps.rawParam(i); ok {
+					return value, true
+				}
+			}
+			value, ok := ps.findParam(i)
+			if decode {
+				value = unescapeParam(value)
+			}
+			return value, ok
+		}
 	}
-	if i, ok := ps.handler.params[name]; ok {
-		return ps.findParam(i)
+	if ps.host != nil {
+		return ps.host.param(ps.hostname, name)
 	}
 	return "", false
 }
 
-func (ps *Params) findParam(paramIndex int) (string, bool) {
-	if ps.node == nil || ps.handler == nil {
-		return "", false
+// unescapeParam decodes the percent-encoded param value.
+// Malformed values are returned as is.
+func unescapeParam(value string) string {
+	if strings.IndexByte(value, '%') == -1 {
+		return value
 	}
+	if s, err := url.PathUnescape(value); err == nil {
+		return s
+	}
+	return value
+}
 
-	path := ps.path
-	pathLen := len(path)
-	if pathLen == 0 {
-		return "", false
+// decodePath decodes the escaped path except for encoded slashes and percent signs,
+// so static parts and constraints match the decoded text, but encoded slashes
+// don't split path segments.
+func decodePath(path string) string {
+	i := strings.IndexByte(path, '%')
+	if i == -1 {
+		return path
+	}
+
+	b := make([]byte, 0, len(path))
+	b = append(b, path[:i]...)
+	for i < len(path) {
+		if c, ok := decodeEscape(path, i); ok {
+			b = append(b, c)
+			i += 3
+			continue
+		}
+		b = append(b, path[i])
+		i++
 	}
+	return string(b)
+}
 
-	currNode := ps.node
-	currParamIndex := len(ps.handler.params) - 1
+// decodeEscape decodes the escape at the index i unless it is a slash or a percent sign.
+func decodeEscape(path string, i int) (byte, bool) {
+	if path[i] != '%' || i+2 >= len(path) {
+		return 0, false
+	}
+	hi, ok1 := unhex(path[i+1])
+	lo, ok2 := unhex(path[i+2])
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	c := hi<<4 | lo
+	if c == '/' || c == '%' {
+		return 0, false
+	}
+	return c, true
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
 
-	if paramIndex < 0 || paramIndex > currParamIndex {
+// rawParam returns the value of the param as it appears in the escaped request path.
+// It fails when the matched path is not the decoded request path, for example,
+// when the route is served for the cleaned path.
+func (ps *Params) rawParam(paramIndex int) (string, bool) {
+	if !ps.hasOffsets() || paramIndex < 0 || paramIndex >= int(ps.offsets.n) {
+		return "", false
+	}
+	if ps.raw == ps.path {
+		return ps.offsets.value(ps.path, paramIndex), true
+	}
+	// The matched path may differ in case, see WithCaseInsensitive.
+	if decoded := decodePath(ps.raw); len(decoded) != len(ps.path) || !strings.EqualFold(decoded, ps.path) {
 		return "", false
 	}
 
-	// Wildcard can be only in the final node.
-	if ps.node.isWC {
-		if currParamIndex == paramIndex {
-			if int(ps.wildcardLen) > pathLen {
-				return "", false
-			}
-			pathLen -= int(ps.wildcardLen)
-			return path[pathLen:], true
-		}
+	pos := ps.offsets.pos[int(ps.offsets.n)-1-paramIndex]
+	start := rawIndex(ps.raw, int(pos[0]))
+	end := rawIndex(ps.raw, int(pos[1]))
+	return ps.raw[start:end], true
+}
 
-		currParamIndex--
-		if int(ps.wildcardLen) > pathLen {
-			return "", false
+// rawIndex converts the index in the decoded path to the index in the escaped path.
+func rawIndex(raw string, i int) int {
+	var j int
+	for ; i > 0; i-- {
+		if _, ok := decodeEscape(raw, j); ok {
+			j += 3
+		} else {
+			j++
 		}
-		pathLen -= int(ps.wildcardLen)
-		path = path[:pathLen]
 	}
+	return j
+}
 
-	for currNode != nil {
-		if currNode.part[0] != ':' { // static node
-			partLen := len(currNode.part)
-			if partLen > pathLen {
-				return "", false
-			}
-			pathLen -= partLen
-			path = path[:pathLen]
-			currNode = currNode.parent
-			continue
-		}
-
-		i := strings.LastIndexByte(path, '/')
-		if i == -1 {
-			return "", false
-		}
-		pathLen = i + 1
+func (ps *Params) findParam(paramIndex int) (string, bool) {
+	if ps.node == nil || ps.handler == nil {
+		return "", false
+	}
+	if paramIndex < 0 || paramIndex >= len(ps.handler.params) {
+		return "", false
+	}
+	if ps.hasOffsets() {
+		return ps.offsets.value(ps.path, paramIndex), true
+	}
 
-		if currParamIndex == paramIndex {
-			return path[pathLen:], true
-		}
+	var values [maxParams]string
+	m := ps.matcher(&values)
+	if !ps.match(&m) {
+		return "", false
+	}
+	return m.value(paramIndex)
+}
 
-		currParamIndex--
-		path = path[:pathLen]
-		currNode = currNode.parent
+// matcher returns a matcher that repeats the route lookup to capture param values.
+func (ps *Params) matcher(values *[maxParams]string) matcher {
+	return matcher{
+		decode: ps.raw != "",
+		target: ps.node,
+		values: values,
 	}
+}
 
-	return "", false
+// hasOffsets reports whether the offsets of all path params were recorded during the lookup.
+func (ps *Params) hasOffsets() bool {
+	return ps.handler != nil && int(ps.offsets.n) == len(ps.handler.params)
+}
+
+// match captures param values using the recorded offsets
+// or, if there are none, by repeating the route lookup.
+func (ps *Params) match(m *matcher) bool {
+	if ps.tree == nil || ps.node == nil {
+		return false
+	}
+	if ps.hasOffsets() {
+		for i, pos := range ps.offsets.pos[:ps.offsets.n] {
+			m.values[i] = ps.path[pos[0]:pos[1]]
+		}
+		m.numValues = int(ps.offsets.n)
+		return true
+	}
+	_, handler := ps.tree.findRoute(m, ps.path)
+	return handler != nil
 }
 
 // ByName returns the value of the named parameter or empty string if not found.
@@ -265,66 +389,122 @@ func (ps Params) ByName(name string) string {
 }
 
 // Int parses the named parameter as an integer.
+// The returned error is a *ParamError.
 func (ps Params) Int(name string) (int, error) {
-	value := ps.ByName(name)
-	if value == "" {
-		return 0, fmt.Errorf("bunrouter: param '%s' not found", name)
-	}
-	return strconv.Atoi(value)
+	return ParamAs[int](ps, name)
 }
 
 // Uint32 parses the named parameter as an unsigned 32-bit integer.
+// The returned error is a *ParamError.
 func (ps Params) Uint32(name string) (uint32, error) {
-	value := ps.ByName(name)
-	if value == "" {
-		return 0, fmt.Errorf("bunrouter: param '%s' not found", name)
-	}
-	n, err := strconv.ParseUint(value, 10, 32)
-	return uint32(n), err
+	return ParamAs[uint32](ps, name)
 }
 
 // Uint64 parses the named parameter as an unsigned 64-bit integer.
+// The returned error is a *ParamError.
 func (ps Params) Uint64(name string) (uint64, error) {
-	value := ps.ByName(name)
-	if value == "" {
-		return 0, fmt.Errorf("bunrouter: param '%s' not found", name)
-	}
-	return strconv.ParseUint(value, 10, 64)
+	return ParamAs[uint64](ps, name)
 }
 
 // Int32 parses the named parameter as a signed 32-bit integer.
+// The returned error is a *ParamError.
 func (ps Params) Int32(name string) (int32, error) {
-	value := ps.ByName(name)
-	if value == "" {
-		return 0, fmt.Errorf("bunrouter: param '%s' not found", name)
-	}
-	n, err := strconv.ParseInt(value, 10, 32)
-	return int32(n), err
+	return ParamAs[int32](ps, name)
 }
 
 // Int64 parses the named parameter as a signed 64-bit integer.
+// The returned error is a *ParamError.
 func (ps Params) Int64(name string) (int64, error) {
+	return ParamAs[int64](ps, name)
+}
+
+// Bool parses the named parameter as a boolean, for example, "true", "false", "1", or "0".
+// The returned error is a *ParamError.
+func (ps Params) Bool(name string) (bool, error) {
+	return ParamAs[bool](ps, name)
+}
+
+// Float64 parses the named parameter as a 64-bit floating-point number.
+// The returned error is a *ParamError.
+func (ps Params) Float64(name string) (float64, error) {
+	return ParamAs[float64](ps, name)
+}
+
+// UUID parses the named parameter as a UUID in the canonical form,
+// for example, "f47ac10b-58cc-4372-a567-0e02b2c3d479".
+// The returned error is a *ParamError.
+func (ps Params) UUID(name string) ([16]byte, error) {
 	value := ps.ByName(name)
 	if value == "" {
-		return 0, fmt.Errorf("bunrouter: param '%s' not found", name)
+		return [16]byte{}, &ParamError{Name: name, Err: ErrParamNotFound}
+	}
+	uuid, err := parseUUID(value)
+	if err != nil {
+		return uuid, &ParamError{Name: name, Value: value, Err: err}
 	}
-	return strconv.ParseInt(value, 10, 64)
+	return uuid, nil
+}
+
+// Time parses the named parameter as a time in the RFC 3339 format,
+// for example, "2006-01-02T15:04:05Z07:00", or as a date, for example, "2006-01-02".
+// The returned error is a *ParamError.
+func (ps Params) Time(name string) (time.Time, error) {
+	return ParamAs[time.Time](ps, name)
+}
+
+// Duration parses the named parameter as a duration, for example, "1h30m".
+// The returned error is a *ParamError.
+func (ps Params) Duration(name string) (time.Duration, error) {
+	return ParamAs[time.Duration](ps, name)
 }
 
 // Map returns route parameters as a map[string]string.
+// Host params are included as well.
 func (ps Params) Map() map[string]string {
-	if ps.handler == nil || len(ps.handler.params) == 0 {
+	if ps.numParams() == 0 {
 		return make(map[string]string)
 	}
-	m := make(map[string]string, len(ps.handler.params))
-	for param, index := range ps.handler.params {
-		if value, ok := ps.findParam(index); ok {
-			m[param] = value
+
+	m := make(map[string]string, ps.numParams())
+
+	var values [maxParams]string
+	mr := ps.matcher(&values)
+	if ps.handler != nil && ps.match(&mr) {
+		for param, index := range ps.handler.params {
+			if value, ok := mr.value(index); ok {
+				if ps.raw != "" {
+					value = unescapeParam(value)
+				}
+				m[param] = value
+			}
+		}
+	}
+
+	if ps.host != nil {
+		for param := range ps.host.params {
+			if _, ok := m[param]; ok {
+				continue
+			}
+			if value, ok := ps.host.param(ps.hostname, param); ok {
+				m[param] = value
+			}
 		}
 	}
+
 	return m
 }
 
+func (ps Params) numParams() int {
+	var n int
+	if ps.handler != nil {
+		n += len(ps.handler.params)
+	}
+	if ps.host != nil {
+		n += len(ps.host.params)
+	}
+	return n
+}
+
 // Param represents a key-value pair of route parameters.
 type Param struct {
 	Key   string
@@ -332,16 +512,37 @@ type Param struct {
 }
 
 // Slice returns route parameters as a slice of Param.
+// Host params go after path params.
 func (ps Params) Slice() []Param {
-	if ps.handler == nil || len(ps.handler.params) == 0 {
+	if ps.numParams() == 0 {
 		return []Param{}
 	}
-	slice := make([]Param, len(ps.handler.params))
-	for param, index := range ps.handler.params {
-		if value, ok := ps.findParam(index); ok {
-			slice[index] = Param{Key: param, Value: value}
+	slice := make([]Param, ps.numParams())
+
+	var offset int
+	if ps.handler != nil {
+		var values [maxParams]string
+		m := ps.matcher(&values)
+		ps.match(&m)
+
+		for param, index := range ps.handler.params {
+			if value, ok := m.value(index); ok {
+				if ps.raw != "" {
+					value = unescapeParam(value)
+				}
+				slice[index] = Param{Key: param, Value: value}
+			}
 		}
+		offset = len(ps.handler.params)
 	}
+
+	if ps.host != nil {
+		for param, index := range ps.host.params {
+			value, _ := ps.host.param(ps.hostname, param)
+			slice[offset+index] = Param{Key: param, Value: value}
+		}
+	}
+
 	return slice
 }
 
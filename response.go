@@ -0,0 +1,152 @@
+package bunrouter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HeaderWritten reports whether the response headers were already written,
+// so an error handler should not call WriteHeader again. It only knows about
+// responses written through ResponseWriter, for example, with WithResponseWriter
+// or WithErrorHandler; otherwise, it returns false.
+func HeaderWritten(w http.ResponseWriter) bool {
+	for {
+		switch rw := w.(type) {
+		case interface{ HeaderWritten() bool }:
+			return rw.HeaderWritten()
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// ResponseWriter wraps http.ResponseWriter to record the status code, the number of bytes
+// written, and the time to first byte. The router wraps responses with WithResponseWriter
+// and WithErrorHandler, and middlewares can get the wrapper with Request.ResponseWriter.
+//
+// ResponseWriter implements http.Flusher, http.Hijacker, and io.ReaderFrom, and
+// supports http.ResponseController by unwrapping to the original writer.
+type ResponseWriter struct {
+	http.ResponseWriter
+
+	statusCode   int
+	bytesWritten int64
+	wroteHeader  bool
+	start        time.Time
+	firstByte    time.Time
+}
+
+var (
+	_ http.Flusher  = (*ResponseWriter)(nil)
+	_ http.Hijacker = (*ResponseWriter)(nil)
+	_ io.ReaderFrom = (*ResponseWriter)(nil)
+)
+
+// NewResponseWriter wraps the writer. The time to first byte is measured from now.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{
+		ResponseWriter: w,
+		start:          time.Now(),
+	}
+}
+
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// StatusCode returns the status code of the response. It returns http.StatusOK
+// when the body was written without calling WriteHeader and 0 when nothing
+// was written yet.
+func (w *ResponseWriter) StatusCode() int {
+	return w.statusCode
+}
+
+// BytesWritten returns the number of bytes of the response body written so far.
+func (w *ResponseWriter) BytesWritten() int64 {
+	return w.bytesWritten
+}
+
+// HeaderWritten reports whether the response headers were written.
+func (w *ResponseWriter) HeaderWritten() bool {
+	return w.wroteHeader
+}
+
+// TimeToFirstByte returns the time between wrapping the writer and writing
+// the response headers or 0 when they were not written yet.
+func (w *ResponseWriter) TimeToFirstByte() time.Duration {
+	if !w.wroteHeader {
+		return 0
+	}
+	return w.firstByte.Sub(w.start)
+}
+
+func (w *ResponseWriter) writeHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.statusCode = statusCode
+		w.firstByte = time.Now()
+	}
+}
+
+func (w *ResponseWriter) WriteHeader(statusCode int) {
+	// Informational responses can be followed by the final response.
+	if statusCode >= http.StatusOK || statusCode == http.StatusSwitchingProtocols {
+		w.writeHeader(statusCode)
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	w.writeHeader(http.StatusOK)
+	n, err := w.ResponseWriter.Write(b)
+	w.bytesWritten += int64(n)
+	return n, err
+}
+
+func (w *ResponseWriter) WriteString(s string) (int, error) {
+	w.writeHeader(http.StatusOK)
+	n, err := io.WriteString(w.ResponseWriter, s)
+	w.bytesWritten += int64(n)
+	return n, err
+}
+
+// ReadFrom uses io.ReaderFrom of the wrapped writer, if any,
+// so net/http can use sendfile for files.
+func (w *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.writeHeader(http.StatusOK)
+
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.bytesWritten += n
+	return n, err
+}
+
+func (w *ResponseWriter) Flush() {
+	w.writeHeader(http.StatusOK)
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("bunrouter: %T does not implement http.Hijacker", w.ResponseWriter)
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.writeHeader(http.StatusSwitchingProtocols)
+	}
+	return conn, rw, err
+}
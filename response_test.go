@@ -0,0 +1,148 @@
+package bunrouter
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseWriter(t *testing.T) {
+	type Stats struct {
+		status        int
+		bytes         int64
+		headerWritten bool
+	}
+	var stats Stats
+
+	middleware := func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, req Request) error {
+			err := next(w, req.WithContext(req.Context()))
+			rw := req.ResponseWriter()
+			stats = Stats{rw.StatusCode(), rw.BytesWritten(), rw.HeaderWritten()}
+			if rw.HeaderWritten() {
+				require.Greater(t, rw.TimeToFirstByte(), time.Duration(0))
+			}
+			return err
+		}
+	}
+
+	router := New(WithResponseWriter(), Use(middleware))
+	router.GET("/created", func(w http.ResponseWriter, req Request) error {
+		w.WriteHeader(http.StatusCreated)
+		_, err := io.WriteString(w, "hello")
+		return err
+	})
+	router.GET("/body", func(w http.ResponseWriter, req Request) error {
+		_, err := w.Write([]byte("hello world"))
+		return err
+	})
+	router.GET("/copy", func(w http.ResponseWriter, req Request) error {
+		_, err := io.Copy(w, strings.NewReader("copied"))
+		return err
+	})
+	router.GET("/flush", func(w http.ResponseWriter, req Request) error {
+		return http.NewResponseController(w).Flush()
+	})
+	router.GET("/empty", func(w http.ResponseWriter, req Request) error {
+		return nil
+	})
+
+	type Test struct {
+		path  string
+		stats Stats
+		body  string
+	}
+	tests := []Test{
+		{"/created", Stats{http.StatusCreated, 5, true}, "hello"},
+		{"/body", Stats{http.StatusOK, 11, true}, "hello world"},
+		{"/copy", Stats{http.StatusOK, 6, true}, "copied"},
+		{"/flush", Stats{http.StatusOK, 0, true}, ""},
+		{"/empty", Stats{0, 0, false}, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		require.Equal(t, test.stats, stats, test.path)
+		require.Equal(t, test.body, w.Body.String(), test.path)
+	}
+
+	router = New()
+	router.GET("/", func(w http.ResponseWriter, req Request) error {
+		require.Nil(t, req.ResponseWriter())
+		return nil
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	router := New(WithResponseWriter())
+	router.GET("/", func(w http.ResponseWriter, req Request) error {
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		require.True(t, req.ResponseWriter().HeaderWritten())
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nConnection: close\r\n\r\nhijacked")
+		return buf.Flush()
+	})
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(bufio.NewReader(resp.Body))
+	require.NoError(t, err)
+	require.Equal(t, "hijacked", string(body))
+
+	w := NewResponseWriter(httptest.NewRecorder())
+	_, _, err = w.Hijack()
+	require.Error(t, err)
+
+	w = NewResponseWriter(failingHijacker{httptest.NewRecorder()})
+	_, _, err = w.Hijack()
+	require.Error(t, err)
+	require.False(t, w.HeaderWritten())
+	require.Equal(t, 0, w.StatusCode())
+}
+
+type failingHijacker struct {
+	http.ResponseWriter
+}
+
+func (failingHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("hijack failed")
+}
+
+func TestResponseWriterOutlivesRequest(t *testing.T) {
+	var writers []*ResponseWriter
+	router := New(WithResponseWriter())
+	router.GET("/:code", func(w http.ResponseWriter, req Request) error {
+		writers = append(writers, req.ResponseWriter())
+		code, err := req.Params().Int("code")
+		if err != nil {
+			return err
+		}
+		w.WriteHeader(code)
+		return nil
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/201", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/404", nil))
+
+	require.Len(t, writers, 2)
+	require.Equal(t, http.StatusCreated, writers[0].StatusCode())
+	require.Equal(t, http.StatusNotFound, writers[1].StatusCode())
+}
@@ -0,0 +1,798 @@
+package bunrouter
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// RouteIssueKind is the kind of a problem found by Router.Validate.
+type RouteIssueKind string
+
+const (
+	// ParamConflict means that routes use different param names at the same tree position,
+	// for example, "/users/:id" and "/users/:name/edit".
+	ParamConflict RouteIssueKind = "param conflict"
+	// UnreachableRoute means that requests are always handled by another route
+	// that takes precedence, for example, "/:id<uint>" after "/:id<int>".
+	UnreachableRoute RouteIssueKind = "unreachable route"
+	// RouteOverlap means that some paths match both routes and the precedence rules
+	// decide which one is used, for example, "/users/new" and "/users/:id".
+	RouteOverlap RouteIssueKind = "route overlap"
+)
+
+// RouteIssue describes a problem with a registered route.
+type RouteIssue struct {
+	Kind    RouteIssueKind
+	Method  string
+	Route   string // route pattern
+	Host    string // host pattern or empty string
+	Source  string // file:line where the route was registered
+	Message string
+
+	Other       string // pattern of the other route
+	OtherSource string // file:line where the other route was registered
+}
+
+func (i RouteIssue) String() string {
+	var b strings.Builder
+	b.WriteString(string(i.Kind))
+	b.WriteString(": ")
+	b.WriteString(i.Method)
+	b.WriteByte(' ')
+	b.WriteString(i.Host)
+	b.WriteString(i.Route)
+	if i.Source != "" {
+		fmt.Fprintf(&b, " (%s)", i.Source)
+	}
+	b.WriteString(": ")
+	b.WriteString(i.Message)
+	if i.OtherSource != "" {
+		fmt.Fprintf(&b, " (%s)", i.OtherSource)
+	}
+	return b.String()
+}
+
+// Validate reports routes with conflicting param names, routes that can't be reached
+// because other routes take precedence, and routes that match the same paths.
+// Overlaps are common, for example, "/users/new" and "/users/:id", and are
+// reported for review only. See also WithStrictRoutes.
+func (r *Router) Validate() []RouteIssue {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tables := []*routeTable{&r.routeTable}
+	if p := r.hosts.Load(); p != nil {
+		for _, host := range *p {
+			tables = append(tables, &host.routeTable)
+		}
+	}
+
+	var issues []RouteIssue
+	for _, table := range tables {
+		entries := table.routeEntries()
+		unreachable := table.unreachableRoutes(entries)
+		issues = append(issues, paramConflicts(table.host, entries)...)
+		issues = append(issues, unreachable...)
+		issues = append(issues, skipReported(routeOverlaps(table.host, entries), unreachable)...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		return a.Method < b.Method
+	})
+
+	// Routes with optional segments are reported once.
+	unique := issues[:0]
+	for i, issue := range issues {
+		if i > 0 && issue == issues[i-1] {
+			continue
+		}
+		unique = append(unique, issue)
+	}
+	return unique
+}
+
+// checkStrict panics if the route has issues other than overlaps. Unlike Validate,
+// it only checks the tree path of the route, so registration time doesn't grow
+// with the number of routes. Routes that were checked before are assumed to be valid.
+func (g *Group) checkStrict(meth, path string) {
+	for _, route := range expandRoute(path) {
+		e := g.table.routeEntry(meth, route)
+		if e == nil {
+			continue
+		}
+
+		issue, ok := g.table.paramConflict(e)
+		if !ok {
+			issue, ok = g.table.unreachableRoute(e)
+		}
+		if ok {
+			g.remove(meth, path)
+			panic(fmt.Errorf("bunrouter: %s", issue))
+		}
+	}
+}
+
+// routeEntry returns the entry of the route handler or nil.
+func (t *routeTable) routeEntry(meth, route string) *routeEntry {
+	parts, _ := splitRoute(route, t.inlineParams)
+
+	var tokens []routeToken
+	var params []paramKey
+	n := t.tree.findParts(parts, func(n *node) {
+		tokens, params = appendNodeToken(tokens, params, n)
+	})
+	if n == nil || n.handlerMap == nil {
+		return nil
+	}
+
+	h := n.handlerMap.Get(meth)
+	if h == nil || h.implicit {
+		return nil
+	}
+	return newRouteEntry(n, meth, h, tokens, params)
+}
+
+// paramConflict checks the route params against another route that has
+// the param at the same tree position.
+func (t *routeTable) paramConflict(e *routeEntry) (RouteIssue, bool) {
+	for i, key := range e.params {
+		accept := func(h *routeHandler) bool {
+			return h.route != e.handler.route
+		}
+		if key.terminal {
+			accept = func(h *routeHandler) bool {
+				return h.route != e.handler.route && len(h.params) > i
+			}
+		}
+
+		meth, other := key.node.findHandler(key.terminal, accept)
+		if other == nil {
+			continue
+		}
+
+		name, otherName := e.paramName(i), handlerParamName(other, i)
+		if name == otherName {
+			continue
+		}
+		return RouteIssue{
+			Kind:        ParamConflict,
+			Method:      e.method,
+			Route:       e.handler.route,
+			Host:        t.host,
+			Source:      e.handler.source,
+			Message:     fmt.Sprintf("param %q is named %q in %s %s", name, otherName, meth, other.route),
+			Other:       other.route,
+			OtherSource: other.source,
+		}, true
+	}
+	return RouteIssue{}, false
+}
+
+// unreachableRoute checks whether the route can't be reached because of a preceding
+// param node with a wider constraint, for example, "/:id<uint>" after "/:id<int>",
+// or makes another route unreachable.
+func (t *routeTable) unreachableRoute(e *routeEntry) (RouteIssue, bool) {
+	for i, tok := range e.tokens {
+		if !tok.param || tok.constraint == nil {
+			continue
+		}
+		parent := e.tokens[i-1].node
+		rel := relParts(e.tokens[i+1:])
+
+		preceding := true
+		for _, colon := range parent.colons {
+			if colon == tok.node {
+				preceding = false
+				continue
+			}
+			if colon.constraint == nil {
+				continue
+			}
+
+			var covers bool
+			if preceding {
+				covers = constraintCovers(colon.constraint, tok.constraint, t.constraints)
+			} else {
+				covers = constraintCovers(tok.constraint, colon.constraint, t.constraints)
+			}
+			if !covers {
+				continue
+			}
+
+			n := colon.findParts(rel, nil)
+			if n == nil || n.handlerMap == nil {
+				continue
+			}
+			other := n.handlerMap.Get(e.method)
+			if other == nil || other.implicit || len(other.params) != len(e.handler.params) {
+				continue
+			}
+
+			prev, next := other, e.handler
+			if !preceding {
+				prev, next = next, prev
+			}
+			return RouteIssue{
+				Kind:   UnreachableRoute,
+				Method: e.method,
+				Route:  next.route,
+				Host:   t.host,
+				Source: next.source,
+				Message: fmt.Sprintf("%s %s handles the same paths and takes precedence",
+					e.method, prev.route),
+				Other:       prev.route,
+				OtherSource: prev.source,
+			}, true
+		}
+	}
+	return RouteIssue{}, false
+}
+
+// relParts converts the tokens back to the route parts, see splitRoute.
+func relParts(tokens []routeToken) []string {
+	var parts []string
+	var static strings.Builder
+	flush := func() {
+		if static.Len() > 0 {
+			parts = append(parts, static.String())
+			static.Reset()
+		}
+	}
+
+	for _, tok := range tokens {
+		switch {
+		case tok.param:
+			flush()
+			parts = append(parts, ":"+tok.constraint.spec())
+		case tok.wildcard:
+			flush()
+			parts = append(parts, "*")
+		default:
+			static.WriteString(tok.static)
+		}
+	}
+	flush()
+	return parts
+}
+
+//------------------------------------------------------------------------------
+
+// routeEntry is a route handler found in the routing tree.
+type routeEntry struct {
+	method  string
+	handler *routeHandler
+	tokens  []routeToken
+	params  []paramKey // param positions in the tree
+}
+
+// routeToken is a part of the route: a static text, a param, or a wildcard.
+type routeToken struct {
+	node       *node
+	static     string
+	param      bool
+	wildcard   bool
+	terminal   bool
+	constraint *paramConstraint
+}
+
+// paramKey identifies the tree position of a param.
+type paramKey struct {
+	node     *node
+	terminal bool // terminal wildcard of the node
+}
+
+func (e *routeEntry) paramName(i int) string {
+	return handlerParamName(e.handler, i)
+}
+
+func handlerParamName(h *routeHandler, i int) string {
+	for name, index := range h.params {
+		if index == i {
+			return name
+		}
+	}
+	return ""
+}
+
+// appendNodeToken appends the token and the param key, if any, of the node.
+func appendNodeToken(tokens []routeToken, params []paramKey, n *node) ([]routeToken, []paramKey) {
+	switch {
+	case n.part == ":":
+		tokens = append(tokens, routeToken{node: n, param: true, constraint: n.constraint})
+		params = append(params, paramKey{node: n})
+	case n.part == "*":
+		tokens = append(tokens, routeToken{node: n, wildcard: true})
+		params = append(params, paramKey{node: n})
+	default:
+		tokens = append(tokens, routeToken{node: n, static: n.part})
+	}
+	return tokens, params
+}
+
+// newRouteEntry returns the entry of the handler registered at the node.
+func newRouteEntry(n *node, meth string, h *routeHandler, tokens []routeToken, params []paramKey) *routeEntry {
+	e := &routeEntry{
+		method:  meth,
+		handler: h,
+		tokens:  append([]routeToken(nil), tokens...),
+		params:  append([]paramKey(nil), params...),
+	}
+	if n.isWC && len(h.params) > len(params) {
+		e.tokens = append(e.tokens, routeToken{node: n, wildcard: true, terminal: true})
+		e.params = append(e.params, paramKey{node: n, terminal: true})
+	}
+	return e
+}
+
+// findHandler returns the first explicitly registered handler accepted by the fn.
+// Unless only is set, the whole subtree is searched.
+func (n *node) findHandler(only bool, fn func(h *routeHandler) bool) (string, *routeHandler) {
+	if n.handlerMap != nil {
+		for _, meth := range n.handlerMap.Methods() {
+			if h := n.handlerMap.Get(meth); h != nil && !h.implicit && fn(h) {
+				return meth, h
+			}
+		}
+	}
+	if only {
+		return "", nil
+	}
+
+	for _, child := range n.nodes {
+		if meth, h := child.findHandler(false, fn); h != nil {
+			return meth, h
+		}
+	}
+	for _, colon := range n.colons {
+		if meth, h := colon.findHandler(false, fn); h != nil {
+			return meth, h
+		}
+	}
+	if n.wildcard != nil {
+		return n.wildcard.findHandler(false, fn)
+	}
+	return "", nil
+}
+
+func (t *routeTable) routeEntries() []*routeEntry {
+	var entries []*routeEntry
+	t.tree.walkRoutes(nil, nil, func(e *routeEntry) {
+		entries = append(entries, e)
+	})
+	return entries
+}
+
+// walkRoutes calls the fn for each explicitly registered handler in the subtree.
+func (n *node) walkRoutes(tokens []routeToken, params []paramKey, fn func(e *routeEntry)) {
+	tokens, params = appendNodeToken(tokens, params, n)
+
+	if n.handlerMap != nil {
+		for _, meth := range n.handlerMap.Methods() {
+			h := n.handlerMap.Get(meth)
+			if h == nil || h.implicit {
+				continue
+			}
+			fn(newRouteEntry(n, meth, h, tokens, params))
+		}
+	}
+
+	for _, child := range n.nodes {
+		child.walkRoutes(tokens, params, fn)
+	}
+	for _, colon := range n.colons {
+		colon.walkRoutes(tokens, params, fn)
+	}
+	if n.wildcard != nil {
+		n.wildcard.walkRoutes(tokens, params, fn)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func paramConflicts(host string, entries []*routeEntry) []RouteIssue {
+	type paramName struct {
+		name  string
+		entry *routeEntry
+	}
+
+	var issues []RouteIssue
+	seen := make(map[paramKey][]paramName)
+	for _, e := range entries {
+		for i, key := range e.params {
+			name := e.paramName(i)
+
+			var conflict *routeEntry
+			var found bool
+			for _, other := range seen[key] {
+				if other.name == name {
+					found = true
+				} else if conflict == nil {
+					conflict = other.entry
+				}
+			}
+			if !found {
+				seen[key] = append(seen[key], paramName{name: name, entry: e})
+			}
+			if conflict == nil || found {
+				continue
+			}
+
+			issues = append(issues, RouteIssue{
+				Kind:   ParamConflict,
+				Method: e.method,
+				Route:  e.handler.route,
+				Host:   host,
+				Source: e.handler.source,
+				Message: fmt.Sprintf("param %q is named %q in %s %s",
+					name, conflict.paramName(i), conflict.method, conflict.handler.route),
+				Other:       conflict.handler.route,
+				OtherSource: conflict.handler.source,
+			})
+		}
+	}
+	return issues
+}
+
+// unreachableRoutes reports each unreachable route once, see unreachableRoute.
+func (t *routeTable) unreachableRoutes(entries []*routeEntry) []RouteIssue {
+	var issues []RouteIssue
+	seen := make(map[RouteIssue]bool)
+	for _, e := range entries {
+		issue, ok := t.unreachableRoute(e)
+		if ok && !seen[issue] {
+			seen[issue] = true
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// constraintSubsets lists the built-in constraints that accept a subset of values
+// accepted by another built-in constraint.
+var constraintSubsets = map[string][]string{
+	"int":   {"uint"},
+	"hex":   {"uint"},
+	"alnum": {"alpha", "uint", "hex"},
+}
+
+func constraintCovers(a, b *paramConstraint, custom map[string]func(string) bool) bool {
+	if _, ok := custom[a.text]; ok {
+		return false
+	}
+	if _, ok := custom[b.text]; ok {
+		return false
+	}
+	for _, subset := range constraintSubsets[a.text] {
+		if subset == b.text {
+			return true
+		}
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+type routeSegment []routeToken
+
+func routeOverlaps(host string, entries []*routeEntry) []RouteIssue {
+	byMethod := make(map[string][]*routeEntry)
+	var methods []string
+	for _, e := range entries {
+		if _, ok := byMethod[e.method]; !ok {
+			methods = append(methods, e.method)
+		}
+		byMethod[e.method] = append(byMethod[e.method], e)
+	}
+
+	var issues []RouteIssue
+	for _, meth := range methods {
+		routes := make([]*segmentedRoute, len(byMethod[meth]))
+		for i, e := range byMethod[meth] {
+			routes[i] = &segmentedRoute{entry: e, segments: splitSegments(e.tokens)}
+		}
+
+		findOverlaps(routes, 0, func(a, b *segmentedRoute) {
+			if !a.entry.precedes(b.entry) {
+				a, b = b, a
+			}
+			issues = append(issues, RouteIssue{
+				Kind:   RouteOverlap,
+				Method: meth,
+				Route:  b.entry.handler.route,
+				Host:   host,
+				Source: b.entry.handler.source,
+				Message: fmt.Sprintf("some paths also match %s, which takes precedence",
+					a.entry.handler.route),
+				Other:       a.entry.handler.route,
+				OtherSource: a.entry.handler.source,
+			})
+		})
+	}
+	return issues
+}
+
+// skipReported removes the overlaps of the routes that are already reported as unreachable.
+func skipReported(overlaps, unreachable []RouteIssue) []RouteIssue {
+	type pair struct {
+		method, route, other string
+	}
+	reported := make(map[pair]bool, 2*len(unreachable))
+	for _, issue := range unreachable {
+		reported[pair{issue.Method, issue.Route, issue.Other}] = true
+		reported[pair{issue.Method, issue.Other, issue.Route}] = true
+	}
+
+	filtered := overlaps[:0]
+	for _, issue := range overlaps {
+		if !reported[pair{issue.Method, issue.Route, issue.Other}] {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
+
+// precedes reports whether the route is tried before the other route:
+// static parts go first, then params with constraints, params, and wildcards.
+func (e *routeEntry) precedes(other *routeEntry) bool {
+	for i, tok := range e.tokens {
+		if i >= len(other.tokens) {
+			return false
+		}
+		otherTok := other.tokens[i]
+		if tok == otherTok {
+			continue
+		}
+
+		if rank, otherRank := tok.rank(), otherTok.rank(); rank != otherRank {
+			return rank < otherRank
+		}
+		if tok.param && i > 0 {
+			// Param nodes with constraints are tried in the order they were added.
+			parent := e.tokens[i-1].node
+			for _, colon := range parent.colons {
+				if colon == tok.node {
+					return true
+				}
+				if colon == otherTok.node {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return true
+}
+
+func (tok routeToken) rank() int {
+	switch {
+	case tok.param && tok.constraint != nil:
+		return 1
+	case tok.param:
+		return 2
+	case tok.terminal:
+		return 4
+	case tok.wildcard:
+		return 3
+	default:
+		return 0
+	}
+}
+
+type segmentedRoute struct {
+	entry    *routeEntry
+	segments []routeSegment
+}
+
+// splitSegments splits the tokens at slashes. The leading slash is dropped.
+func splitSegments(tokens []routeToken) []routeSegment {
+	var segments []routeSegment
+	var segment routeSegment
+	for _, tok := range tokens {
+		if tok.param || tok.wildcard {
+			segment = append(segment, tok)
+			continue
+		}
+		parts := strings.Split(tok.static, "/")
+		for i, part := range parts {
+			if i > 0 {
+				segments = append(segments, segment)
+				segment = nil
+			}
+			if part != "" {
+				segment = append(segment, routeToken{static: part})
+			}
+		}
+	}
+	segments = append(segments, segment)
+	return segments[1:]
+}
+
+// findOverlaps calls the fn for each pair of routes that match the same path.
+// Routes are grouped by static segments so only routes that share the static
+// prefix are compared with each other.
+func findOverlaps(routes []*segmentedRoute, i int, fn func(a, b *segmentedRoute)) {
+	if len(routes) < 2 {
+		return
+	}
+
+	static := make(map[string][]*segmentedRoute)
+	var keys []string
+	var dynamic []*segmentedRoute
+	for _, r := range routes {
+		key, ok := "end", i >= len(r.segments)
+		if !ok {
+			key, ok = r.segments[i].staticText()
+			key = "/" + key
+		}
+		if !ok {
+			dynamic = append(dynamic, r)
+			continue
+		}
+		if _, ok := static[key]; !ok {
+			keys = append(keys, key)
+		}
+		static[key] = append(static[key], r)
+	}
+
+	for _, key := range keys {
+		if key != "end" {
+			findOverlaps(static[key], i+1, fn)
+		}
+	}
+
+	for j, a := range dynamic {
+		for _, b := range routes {
+			if b == a || isDynamic(b, i) && indexOf(dynamic, b) < j {
+				continue
+			}
+			if a.entry.handler.route == b.entry.handler.route {
+				continue
+			}
+			if segmentsOverlap(a.segments[i:], b.segments[i:]) {
+				fn(a, b)
+			}
+		}
+	}
+}
+
+func isDynamic(r *segmentedRoute, i int) bool {
+	if i >= len(r.segments) {
+		return false
+	}
+	_, ok := r.segments[i].staticText()
+	return !ok
+}
+
+func indexOf(routes []*segmentedRoute, r *segmentedRoute) int {
+	for i, other := range routes {
+		if other == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s routeSegment) staticText() (string, bool) {
+	var b strings.Builder
+	for _, tok := range s {
+		if tok.param || tok.wildcard {
+			return "", false
+		}
+		b.WriteString(tok.static)
+	}
+	return b.String(), true
+}
+
+func (s routeSegment) isWildcard() bool {
+	return len(s) == 1 && s[0].wildcard
+}
+
+func segmentsOverlap(a, b []routeSegment) bool {
+	switch {
+	case len(a) == 0 || len(b) == 0:
+		return len(a) == 0 && len(b) == 0
+	case a[0].isWildcard() && len(a) == 1, b[0].isWildcard() && len(b) == 1:
+		return true
+	case a[0].isWildcard():
+		for k := 1; k <= len(b); k++ {
+			if segmentsOverlap(a[1:], b[k:]) {
+				return true
+			}
+		}
+		return false
+	case b[0].isWildcard():
+		return segmentsOverlap(b, a)
+	default:
+		return segmentOverlap(a[0], b[0]) && segmentsOverlap(a[1:], b[1:])
+	}
+}
+
+// segmentOverlap reports whether some text matches both segments.
+// Segments with params on both sides are compared by their static prefixes and suffixes.
+func segmentOverlap(a, b routeSegment) bool {
+	textA, staticA := a.staticText()
+	textB, staticB := b.staticText()
+	switch {
+	case staticA && staticB:
+		return textA == textB
+	case staticA:
+		return b.match(textA)
+	case staticB:
+		return a.match(textB)
+	}
+
+	prefixA, prefixB := a.staticPrefix(), b.staticPrefix()
+	if !strings.HasPrefix(prefixA, prefixB) && !strings.HasPrefix(prefixB, prefixA) {
+		return false
+	}
+	suffixA, suffixB := a.staticSuffix(), b.staticSuffix()
+	return strings.HasSuffix(suffixA, suffixB) || strings.HasSuffix(suffixB, suffixA)
+}
+
+// match reports whether the segment matches the text.
+func (s routeSegment) match(text string) bool {
+	if len(s) == 0 {
+		return text == ""
+	}
+
+	tok := s[0]
+	if !tok.param {
+		return strings.HasPrefix(text, tok.static) && s[1:].match(text[len(tok.static):])
+	}
+	for i := 1; i <= len(text); i++ {
+		if tok.constraint.match(text[:i]) && s[1:].match(text[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s routeSegment) staticPrefix() string {
+	if len(s) > 0 && !s[0].param {
+		return s[0].static
+	}
+	return ""
+}
+
+func (s routeSegment) staticSuffix() string {
+	if len(s) > 0 && !s[len(s)-1].param {
+		return s[len(s)-1].static
+	}
+	return ""
+}
+
+//------------------------------------------------------------------------------
+
+// callerSource returns the file:line of the first caller outside of this package.
+func callerSource() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkgPrefix) ||
+			strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// pkgPrefix is the prefix of the function names in this package,
+// for example, "github.com/uptrace/bunrouter.".
+var pkgPrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	return name[:strings.LastIndex(name, "bunrouter.")+len("bunrouter.")]
+}()
@@ -0,0 +1,108 @@
+package bunrouter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", simpleHandler)
+	router.GET("/users/:name/edit", simpleHandler)
+	router.GET("/users/new", simpleHandler)
+	router.GET("/items/:id<int>", simpleHandler)
+	router.GET("/items/:id<uint>", simpleHandler)
+	router.GET("/orders/:id<uint>", simpleHandler)
+	router.GET("/orders/:id<int>", simpleHandler)
+	router.GET("/tags/:id<int>", simpleHandler)
+	router.GET("/tags/:name<alpha>", simpleHandler)
+	router.GET("/files/:name", simpleHandler)
+	router.GET("/files/*path", simpleHandler)
+	router.Host(":tenant.example.com").GET("/", simpleHandler)
+
+	type Issue struct {
+		Kind  RouteIssueKind
+		Route string
+		Other string
+	}
+
+	var issues []Issue
+	for _, issue := range router.Validate() {
+		issues = append(issues, Issue{issue.Kind, issue.Route, issue.Other})
+
+		require.Equal(t, "routecheck_test.go", filepath.Base(strings.Split(issue.Source, ":")[0]))
+		require.NotEmpty(t, issue.Message)
+	}
+
+	require.Equal(t, []Issue{
+		{RouteOverlap, "/files/*path", "/files/:name"},
+		{UnreachableRoute, "/items/:id<uint>", "/items/:id<int>"},
+		{RouteOverlap, "/orders/:id<int>", "/orders/:id<uint>"},
+		{RouteOverlap, "/tags/:name<alpha>", "/tags/:id<int>"},
+		{RouteOverlap, "/users/:id", "/users/new"},
+		{ParamConflict, "/users/:name/edit", "/users/:id"},
+	}, issues)
+}
+
+func TestStrictRoutes(t *testing.T) {
+	router := New(WithStrictRoutes())
+	router.GET("/users/:id", simpleHandler)
+	router.GET("/users/new", simpleHandler)
+	router.GET("/items/:id<int>", simpleHandler)
+
+	require.Panics(t, func() {
+		router.GET("/users/:name/edit", simpleHandler)
+	})
+	require.Panics(t, func() {
+		router.GET("/items/:id<uint>", simpleHandler)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/1/edit", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	require.Len(t, router.Routes(), 3)
+}
+
+func TestStrictRoutesIncremental(t *testing.T) {
+	router := New(WithStrictRoutes())
+	router.GET("/orders/:id<int>/items", simpleHandler)
+	router.GET("/orders/:id<uint>/lines", simpleHandler)
+	router.GET("/users/:id/posts/:post", simpleHandler)
+	router.Host(":tenant.example.com").GET("/", simpleHandler)
+
+	// The new route is unreachable because of the existing route.
+	require.Panics(t, func() {
+		router.GET("/orders/:id<uint>/items", simpleHandler)
+	})
+	require.Panics(t, func() {
+		router.GET("/users/:name/posts", simpleHandler)
+	})
+	// The new route makes the existing route unreachable.
+	var err error
+	func() {
+		defer func() {
+			err, _ = recover().(error)
+		}()
+		router.GET("/orders/:id<int>/lines", simpleHandler)
+	}()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unreachable route: GET /orders/:id<uint>/lines (")
+	require.Contains(t, err.Error(), "GET /orders/:id<int>/lines handles the same paths")
+
+	router.GET("/orders/:id<int>/items/:item", simpleHandler)
+	router.GET("/orders/:id<int>/notes", simpleHandler)
+	router.GET("/users/:id/posts", simpleHandler)
+
+	for i := 0; i < 1000; i++ {
+		router.GET(fmt.Sprintf("/many/%d/:id", i), simpleHandler)
+	}
+	require.Empty(t, router.Validate())
+}
@@ -1,19 +1,27 @@
 package bunrouter
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Router is the main router structure that implements HTTP request routing.
 // It maintains a routing tree and handles incoming HTTP requests.
 type Router struct {
-	config            // embedded router configuration
-	Group             // embedded route group
-	mu     sync.Mutex // protects the routing tree
-	tree   node       // root node of the routing tree
+	routeTable            // embedded routes for any host
+	Group                 // embedded route group
+	mu         sync.Mutex // serializes changes of the routing trees
+	hosts      atomic.Pointer[[]*hostRoutes]
+	routes     []RouteInfo
+	names      map[string]*namedRoute
+
+	errorMappings atomic.Pointer[[]errorMapping] // see MapError
 }
 
 // New creates and returns a new Router instance with the given options.
@@ -21,14 +29,18 @@ type Router struct {
 // and other router configurations.
 func New(opts ...Option) *Router {
 	r := &Router{
-		tree: node{
-			part: "/",
+		routeTable: routeTable{
+			tree: node{
+				part: "/",
+			},
 		},
 	}
 
 	r.Group.router = r
+	r.Group.table = &r.routeTable
 	r.config.group = &r.Group
 	r.methodNotAllowedHandler = methodNotAllowedHandler
+	r.publish()
 
 	for _, opt := range opts {
 		opt.apply(&r.config)
@@ -47,76 +59,277 @@ var _ http.Handler = (*Router)(nil)
 // ServeHTTP implements the http.Handler interface.
 // It processes the incoming HTTP request and routes it to the appropriate handler.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	_ = r.ServeHTTPError(w, req)
+	if !r.wrapResponse() {
+		request := Request{Request: req}
+		handler := r.lookup(w, req, &request.params)
+		_ = handler(w, request)
+		return
+	}
+
+	rw := NewResponseWriter(w)
+	var request Request
+	if err := r.serve(rw, req, rw, &request); err != nil {
+		r.handleError(rw, request, err)
+	}
+}
+
+// handleError passes the error to the handler set with WithErrorHandler.
+// Without the handler, errors are discarded except for recovered panics, which are
+// logged and replied with 500 Internal Server Error without exposing the panic value.
+func (r *Router) handleError(w http.ResponseWriter, req Request, err error) {
+	if r.errorHandler != nil {
+		r.errorHandler(w, req, r.mapError(err))
+		return
+	}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		log.Printf("%s\n%s", panicErr, panicErr.Stack)
+		if !HeaderWritten(w) {
+			code := http.StatusInternalServerError
+			http.Error(w, http.StatusText(code), code)
+		}
+	}
 }
 
 // ServeHTTPError is similar to ServeHTTP but also returns any error
 // that occurred during request handling.
 func (r *Router) ServeHTTPError(w http.ResponseWriter, req *http.Request) error {
-	handler, params := r.lookup(w, req)
-	return handler(w, newRequestParams(req, params))
+	if !r.wrapResponse() {
+		return r.serve(w, req, nil, nil)
+	}
+
+	rw := NewResponseWriter(w)
+	return r.serve(rw, req, rw, nil)
+}
+
+func (r *Router) wrapResponse() bool {
+	return r.responseWriter || r.errorHandler != nil || r.recovery != nil
 }
 
-// lookup finds the appropriate handler and parameters for the given HTTP request.
-// It returns the handler function and parsed route parameters.
-func (r *Router) lookup(w http.ResponseWriter, req *http.Request) (HandlerFunc, Params) {
+// serve calls the handler for the request. The rw is the wrapped w, if any.
+// If out is not nil, it is set to the request passed to the handler.
+func (r *Router) serve(w http.ResponseWriter, req *http.Request, rw *ResponseWriter, out *Request) error {
+	request := Request{Request: req, rw: rw}
+	handler := r.lookup(w, req, &request.params)
+	if out != nil {
+		*out = request
+	}
+
+	if r.recovery != nil {
+		return r.recovery.serve(handler, w, request)
+	}
+	return handler(w, request)
+}
+
+// lookup finds the appropriate handler for the given HTTP request
+// and stores the parsed route parameters in params, which must be zero.
+func (r *Router) lookup(w http.ResponseWriter, req *http.Request, params *Params) HandlerFunc {
 	path := req.URL.RawPath
-	if path == "" {
+	var raw string
+	if r.decodeParams {
+		// RawPath is empty when it matches the default encoding of Path, for example, "%20".
+		raw = req.URL.EscapedPath()
+		path = decodePath(raw)
+	} else if path == "" {
 		path = req.URL.Path
 	}
 
-	node, handler, wildcardLen := r.tree.findRoute(req.Method, path)
+	if hosts := r.hosts.Load(); hosts != nil {
+		if host, hostname := r.findHost(req); host != nil {
+			handler := host.lookup(w, req.Method, path, params)
+			params.host = host
+			params.hostname = hostname
+			params.raw = raw
+			return handler
+		}
+	}
+
+	handler := r.routeTable.lookup(w, req.Method, path, params)
+	params.raw = raw
+	return handler
+}
+
+// Routes returns all registered routes in the order they were registered.
+func (r *Router) Routes() []RouteInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	routes := make([]RouteInfo, len(r.routes))
+	copy(routes, r.routes)
+	return routes
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Name   string   // route name set with WithRouteName
+	Method string   // HTTP method, for example, "GET"
+	Path   string   // route pattern including the group prefix, for example, "/api/users/:id"
+	Params []string // param names in the order they appear in the path
+	Group  string   // prefix of the group that registered the route, for example, "/api"
+	Host   string   // host pattern or empty string for routes that match any host
+}
+
+//------------------------------------------------------------------------------
+
+// routeTable is a routing tree with its own not found and method not allowed handlers.
+//
+// Routes are added to the working tree while holding Router.mu. Lookups use a read-only
+// copy of the tree that is atomically replaced after each change, so they don't need locks.
+type routeTable struct {
+	config
+	tree     node                          // working tree, protected by Router.mu
+	snapshot atomic.Pointer[routeSnapshot] // read-only copy of the tree used by lookups
+	host     string                        // host pattern
+}
+
+// publish makes the changes in the working tree visible to lookups.
+func (t *routeTable) publish() {
+	t.snapshot.Store(newRouteSnapshot(t.tree.freeze()))
+}
+
+func (t *routeTable) lookup(w http.ResponseWriter, method, path string, params *Params) HandlerFunc {
+	snapshot := t.snapshot.Load()
+	root := snapshot.root
+
+	// Fully static routes don't need the tree walk, see minStaticRoutes.
+	if root.numStatic >= minStaticRoutes {
+		if node := snapshot.staticNode(path); node != nil {
+			if handler := node.handlerMap.Get(method); handler != nil {
+				*params = Params{
+					path:    path,
+					tree:    root,
+					node:    node,
+					handler: handler,
+				}
+				return handler.fn
+			}
+		}
+	}
+
+	var cache *lookupCache
+	if t.lookupCacheSize > 0 {
+		cache = snapshot.lookupCache(t.lookupCacheSize)
+		if handler := cache.Get(method, path); handler != nil {
+			return handler
+		}
+	}
+
+	// Offsets are recorded only when the handler is found, so params stay zero otherwise.
+	m := matcher{meth: method, decode: t.decodeParams, offsets: &params.offsets}
+	node, handler := root.findRoute(&m, path)
+
+	if handler == nil && t.caseInsensitive {
+		var offsets paramOffsets
+		fm := matcher{meth: method, decode: t.decodeParams, offsets: &offsets}
+		foldNode, foldHandler, canonicalPath := root.findRouteFold(&fm, path)
+		if foldHandler != nil && t.caseInsensitiveRedirect && foldHandler.redirect.mode != RedirectServe {
+			redir := redirectHandler(canonicalPath, foldHandler.redirect.mode, t.decodeParams)
+			if cache != nil {
+				cache.Set(method, path, redir)
+			}
+			return redir
+		}
+		if foldHandler != nil || node == nil && foldNode != nil {
+			// Params are captured from the canonical path that matches exactly.
+			node, handler, path = foldNode, foldHandler, canonicalPath
+			params.offsets = offsets
+		}
+	}
+
 	if node == nil {
-		if redir := r.redir(req.Method, path); redir != nil {
-			return redir, Params{}
+		if redir, redirParams := t.redir(root, method, path); redir != nil {
+			if cache != nil && redirParams.IsZero() {
+				cache.Set(method, path, redir)
+			}
+			*params = redirParams
+			return redir
+		}
+		if cache != nil {
+			cache.Set(method, path, t.notFoundHandler)
 		}
-		return r.notFoundHandler, Params{}
+		return t.notFoundHandler
 	}
 
 	if handler == nil {
-		if redir := r.redir(req.Method, path); redir != nil {
-			return redir, Params{}
+		if redir, redirParams := t.redir(root, method, path); redir != nil {
+			if cache != nil && redirParams.IsZero() {
+				cache.Set(method, path, redir)
+			}
+			*params = redirParams
+			return redir
 		}
-		handler = node.handlerMap.notAllowed
-	}
 
-	return handler.fn, Params{
-		node:        node,
-		handler:     handler,
-		path:        path,
-		wildcardLen: uint16(wildcardLen),
+		if w != nil {
+			w.Header().Set("Allow", node.handlerMap.allow)
+		}
+		if method == http.MethodOptions && node.handlerMap.autoOptions != nil {
+			handler = node.handlerMap.autoOptions
+		} else {
+			handler = node.handlerMap.notAllowed
+		}
 	}
+
+	params.path = path
+	params.tree = root
+	params.node = node
+	params.handler = handler
+	return handler.fn
 }
 
 // redir handles URL redirects for cleaned paths and trailing slash variations.
 // It returns a redirect handler if a redirect is needed, nil otherwise.
-func (r *Router) redir(method, path string) HandlerFunc {
+// Depending on the redirect policy of the matched route, the returned handler
+// may be the route handler itself.
+func (t *routeTable) redir(root *node, method, path string) (HandlerFunc, Params) {
 	if path == "/" {
-		return nil
+		return nil, Params{}
 	}
 
 	// Path was not found. Try cleaning it up and search again.
 	if cleanPath := CleanPath(path); cleanPath != path {
-		if _, handler, _ := r.tree.findRoute(method, cleanPath); handler != nil {
-			return redirectHandler(cleanPath)
+		if redir, params := t.redirectTo(root, method, cleanPath, false); redir != nil {
+			return redir, params
 		}
 	}
 
 	if strings.HasSuffix(path, "/") {
 		// Try path without a slash.
-		cleanPath := path[:len(path)-1]
-		if _, handler, _ := r.tree.findRoute(method, cleanPath); handler != nil {
-			return redirectHandler(cleanPath)
-		}
-		return nil
+		return t.redirectTo(root, method, path[:len(path)-1], true)
 	}
 
 	// Try path with a slash.
-	cleanPath := path + "/"
-	if _, handler, _ := r.tree.findRoute(method, cleanPath); handler != nil {
-		return redirectHandler(cleanPath)
+	return t.redirectTo(root, method, path+"/", true)
+}
+
+func (t *routeTable) redirectTo(root *node, method, path string, trailingSlash bool) (HandlerFunc, Params) {
+	var offsets paramOffsets
+	m := matcher{meth: method, decode: t.decodeParams, offsets: &offsets}
+	node, handler := root.findRoute(&m, path)
+	if handler == nil && t.caseInsensitive {
+		// Redirect to the path with the canonical casing.
+		m = matcher{meth: method, decode: t.decodeParams, offsets: &offsets}
+		node, handler, path = root.findRouteFold(&m, path)
 	}
-	return nil
+	if handler == nil {
+		return nil, Params{}
+	}
+
+	policy := handler.redirect
+	if trailingSlash && policy.noTrailingSlash || !trailingSlash && policy.noCleanPath {
+		return nil, Params{}
+	}
+
+	if policy.mode == RedirectServe {
+		return handler.fn, Params{
+			path:    path,
+			tree:    root,
+			node:    node,
+			handler: handler,
+			offsets: offsets,
+		}
+	}
+	return redirectHandler(path, policy.mode, t.decodeParams), Params{}
 }
 
 //------------------------------------------------------------------------------
@@ -153,20 +366,148 @@ func (r *Router) Verbose() *VerboseRouter {
 
 // redirectHandler creates a handler function that performs HTTP redirects
 // to the specified new path while preserving query parameters and fragments.
-func redirectHandler(newPath string) HandlerFunc {
+// The new path is escaped like the path the router matched, see Router.lookup.
+func redirectHandler(newPath string, mode RedirectMode, decode bool) HandlerFunc {
 	return func(w http.ResponseWriter, req Request) error {
 		newURL := url.URL{
 			Path:     newPath,
 			RawQuery: req.URL.RawQuery,
 			Fragment: req.URL.Fragment,
 		}
-		http.Redirect(w, req.Request, newURL.String(), http.StatusMovedPermanently)
+		if decode || req.URL.RawPath != "" {
+			rawPath := escapePath(newPath)
+			if path, err := url.PathUnescape(rawPath); err == nil {
+				newURL.Path = path
+				newURL.RawPath = rawPath
+			}
+		}
+		http.Redirect(w, req.Request, newURL.String(), mode.statusCode(req.Method))
 		return nil
 	}
 }
 
+// escapePath escapes the chars that are not allowed in the escaped path.
+// Percent signs are kept as is because they start escapes.
+func escapePath(path string) string {
+	var n int
+	for i := 0; i < len(path); i++ {
+		if shouldEscapePath(path[i]) {
+			n++
+		}
+	}
+	if n == 0 {
+		return path
+	}
+
+	const upperhex = "0123456789ABCDEF"
+	b := make([]byte, 0, len(path)+2*n)
+	for i := 0; i < len(path); i++ {
+		if c := path[i]; shouldEscapePath(c) {
+			b = append(b, '%', upperhex[c>>4], upperhex[c&15])
+		} else {
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+func shouldEscapePath(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return false
+	}
+	switch c {
+	case '-', '.', '_', '~', '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', ':', '@', '/', '%':
+		return false
+	}
+	return true
+}
+
+// headHandler adapts a GET handler to serve HEAD requests by discarding the body.
+func headHandler(next HandlerFunc) HandlerFunc {
+	return func(w http.ResponseWriter, req Request) error {
+		hw := &headResponseWriter{ResponseWriter: w}
+		err := next(hw, req)
+		if err != nil && !hw.written() {
+			// Let the error handling middlewares write the response.
+			return err
+		}
+		hw.writeHeader()
+		return err
+	}
+}
+
+// headResponseWriter discards the response body and delays writing the headers
+// until the handler returns so Content-Length can be set from the body size.
+type headResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	size        int
+	wroteHeader bool
+}
+
+var _ http.Flusher = (*headResponseWriter)(nil)
+
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *headResponseWriter) WriteHeader(statusCode int) {
+	if statusCode < http.StatusOK {
+		// Informational responses are sent immediately.
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	w.size += len(b)
+	return len(b), nil
+}
+
+func (w *headResponseWriter) WriteString(s string) (int, error) {
+	w.size += len(s)
+	return len(s), nil
+}
+
+func (w *headResponseWriter) Flush() {
+	w.writeHeader()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *headResponseWriter) written() bool {
+	return w.wroteHeader || w.statusCode != 0 || w.size > 0
+}
+
+func (w *headResponseWriter) writeHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	h := w.ResponseWriter.Header()
+	if h.Get("Content-Length") == "" && w.size > 0 {
+		h.Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(w.statusCode)
+}
+
+// optionsHandler replies to OPTIONS requests when WithAutoOptions is enabled.
+// The Allow header is set by the router.
+func optionsHandler(w http.ResponseWriter, req Request) error {
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 // methodNotAllowedHandler is the default handler for requests with methods
-// that are not allowed for the matched route.
+// that are not allowed for the matched route. The Allow header is set by the router.
 func methodNotAllowedHandler(w http.ResponseWriter, r Request) error {
 	w.WriteHeader(http.StatusMethodNotAllowed)
 	return nil
@@ -2,11 +2,16 @@ package bunrouter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -161,8 +166,8 @@ func testRedirect(t *testing.T) {
 	router := New()
 	expectedCodeMap := map[string]int{
 		"GET":  http.StatusMovedPermanently,
-		"POST": http.StatusMovedPermanently,
-		"PUT":  http.StatusMovedPermanently,
+		"POST": http.StatusPermanentRedirect,
+		"PUT":  http.StatusPermanentRedirect,
 	}
 
 	router.GET("/slash/", redirHandler)
@@ -282,6 +287,50 @@ func TestRedirectClean(t *testing.T) {
 	require.Equal(t, http.StatusMovedPermanently, w.Code)
 }
 
+func TestRedirectPolicy(t *testing.T) {
+	router := New(WithRedirectMode(RedirectTemporary))
+	router.GET("/temp/", simpleHandler)
+	router.POST("/temp/", simpleHandler)
+	router.NewGroup("/noslash", WithRedirectTrailingSlash(false)).GET("/users", simpleHandler)
+	router.NewGroup("/noclean", WithRedirectCleanPath(false)).GET("/users", simpleHandler)
+	router.NewGroup("/serve", WithRedirectMode(RedirectServe), WithGroup(func(g *Group) {
+		g.GET("/users/:id", func(w http.ResponseWriter, req Request) error {
+			_, err := w.Write([]byte(req.Param("id")))
+			return err
+		})
+	}))
+
+	type Test struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}
+
+	tests := []Test{
+		{"GET", "/temp", http.StatusFound, "/temp/"},
+		{"POST", "/temp", http.StatusTemporaryRedirect, "/temp/"},
+		{"GET", "/noslash/users/", http.StatusNotFound, ""},
+		{"GET", "/noslash/./users", http.StatusFound, "/noslash/users"},
+		{"GET", "/noclean/users/", http.StatusFound, "/noclean/users"},
+		{"GET", "/noclean/./users", http.StatusNotFound, ""},
+		{"GET", "/serve/users/123/", http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		req.URL.Path = test.path
+		router.ServeHTTP(w, req)
+		require.Equal(t, test.code, w.Code, test.path)
+		require.Equal(t, test.location, w.Header().Get("Location"), test.path)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/serve/users/123/", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, "123", w.Body.String())
+}
+
 func TestRoot(t *testing.T) {
 	for _, scenario := range scenarios {
 		t.Log(scenario.description)
@@ -468,6 +517,15 @@ func TestRedirectEscapedPath(t *testing.T) {
 
 	location := w.Header().Get("Location")
 	require.Equal(t, "/Test%20P@th/", location)
+
+	// The escaped path is not escaped again.
+	router.GET("/files/:file", testHandler)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/files/a%2Fb/", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+	require.Equal(t, "/files/a%2Fb", w.Header().Get("Location"))
 }
 
 func TestMiddlewares(t *testing.T) {
@@ -718,7 +776,7 @@ func TestMethodNotAllowedFallthrough(t *testing.T) {
 
 	// 404 cases
 	checkRoute("GET", "/abc", "", "", http.StatusNotFound, map[string]string{})
-	checkRoute("OPTIONS", "/apple", "", "", http.StatusMovedPermanently, map[string]string{})
+	checkRoute("OPTIONS", "/apple", "", "", http.StatusPermanentRedirect, map[string]string{})
 }
 
 func TestWildcardNode(t *testing.T) {
@@ -920,6 +978,123 @@ func TestURIEncodedFilepath(t *testing.T) {
 	})
 }
 
+func TestDecodedParams(t *testing.T) {
+	var params Params
+	handler := func(w http.ResponseWriter, req Request) error {
+		params = req.Params()
+		return nil
+	}
+
+	router := New(WithDecodedParams())
+	router.GET("/files/:file", handler)
+	router.GET("/files/:dir/:file", handler)
+	router.GET("/static/*path", handler)
+
+	type Test struct {
+		path   string
+		route  string
+		params map[string]string
+		raw    map[string]string
+	}
+
+	tests := []Test{
+		{
+			"/files/hello%20world", "/files/:file",
+			map[string]string{"file": "hello world"},
+			map[string]string{"file": "hello%20world"},
+		},
+		{
+			"/files/a%2Fb", "/files/:file",
+			map[string]string{"file": "a/b"},
+			map[string]string{"file": "a%2Fb"},
+		},
+		{
+			"/files/a%2Fb/c%252fd", "/files/:dir/:file",
+			map[string]string{"dir": "a/b", "file": "c%2fd"},
+			map[string]string{"dir": "a%2Fb", "file": "c%252fd"},
+		},
+		{
+			"/files/100%25", "/files/:file",
+			map[string]string{"file": "100%"},
+			map[string]string{"file": "100%25"},
+		},
+		{
+			"/static/a%2Fb/c", "/static/*path",
+			map[string]string{"path": "a/b/c"},
+			map[string]string{"path": "a%2Fb/c"},
+		},
+	}
+	for _, test := range tests {
+		params = Params{}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.path, nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, test.path)
+		require.Equal(t, test.route, params.Route(), test.path)
+		require.Equal(t, test.params, params.Map(), test.path)
+
+		for name, value := range test.params {
+			require.Equal(t, value, params.ByName(name), test.path)
+		}
+		for name, value := range test.raw {
+			require.Equal(t, value, params.RawByName(name), test.path)
+		}
+		for _, param := range params.Slice() {
+			require.Equal(t, test.params[param.Key], param.Value, test.path)
+		}
+	}
+}
+
+func TestDecodedParamsMatching(t *testing.T) {
+	var params Params
+	handler := func(w http.ResponseWriter, req Request) error {
+		params = req.Params()
+		return nil
+	}
+
+	router := New(WithDecodedParams())
+	router.GET("/café", handler)
+	router.GET("/files/:file", handler)
+	router.GET("/names/:name<[a-z ]+>", handler)
+
+	t.Run("static", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/caf%C3%A9", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "/café", params.Route())
+	})
+
+	t.Run("constraint", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/names/hello%20world", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "hello world", params.ByName("name"))
+		require.Equal(t, "hello%20world", params.RawByName("name"))
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/names/hello%2Fworld", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		for path, location := range map[string]string{
+			"/files/hello%20world/": "/files/hello%20world",
+			"/files/a%2Fb/":         "/files/a%2Fb",
+			"/files/100%25/":        "/files/100%25",
+			"/caf%C3%A9/":           "/caf%C3%A9",
+		} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusMovedPermanently, w.Code, path)
+			require.Equal(t, location, w.Header().Get("Location"), path)
+		}
+	})
+}
+
 func TestSplitRoute(t *testing.T) {
 	type Test struct {
 		route  string
@@ -938,22 +1113,52 @@ func TestSplitRoute(t *testing.T) {
 		{"/*path", []string{"*"}, map[string]int{"path": 0}},
 		{"/:foo/*path", []string{":", "/", "*"}, map[string]int{"foo": 0, "path": 1}},
 		{"/:foo/static/*path", []string{":", "/static/", "*"}, map[string]int{"foo": 0, "path": 1}},
+		{"/users/:id<int>", []string{"users/", ":int"}, map[string]int{"id": 0}},
+		{"/files/:name<[a-z]+>/raw", []string{"files/", ":[a-z]+", "/raw"}, map[string]int{"name": 0}},
 		{
 			"/:a/:b/:c/:d/:e",
 			[]string{":", "/", ":", "/", ":", "/", ":", "/", ":"},
 			map[string]int{"a": 0, "b": 1, "c": 2, "d": 3, "e": 4},
 		},
+		{"/users/:user-id", []string{"users/", ":"}, map[string]int{"user-id": 0}},
+		{"/files/:name.json", []string{"files/", ":"}, map[string]int{"name.json": 0}},
+		{"/v1/items:batchGet", []string{"v1/items:batchGet"}, nil},
+		{"/v:version/users", []string{"v:version/users"}, nil},
 	}
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("route=%s", test.route), func(t *testing.T) {
-			parts, params := splitRoute(test.route)
+			parts, params := splitRoute(test.route, false)
+			require.Equal(t, test.parts, parts)
+			require.Equal(t, test.params, params)
+		})
+	}
+
+	inlineTests := []Test{
+		{"/static/:foo/bar", []string{"static/", ":", "/bar"}, map[string]int{"foo": 0}},
+		{"/users/:id<int>", []string{"users/", ":int"}, map[string]int{"id": 0}},
+		{"/files/:name.json", []string{"files/", ":", ".json"}, map[string]int{"name": 0}},
+		{"/v:version/users", []string{"v", ":", "/users"}, map[string]int{"version": 0}},
+		{"/:from-:to", []string{":", "-", ":"}, map[string]int{"from": 0, "to": 1}},
+		{"/:id<int>-edit", []string{":int", "-edit"}, map[string]int{"id": 0}},
+		{"/items\\:batch", []string{"items:batch"}, nil},
+	}
+
+	for _, test := range inlineTests {
+		t.Run(fmt.Sprintf("inline route=%s", test.route), func(t *testing.T) {
+			parts, params := splitRoute(test.route, true)
 			require.Equal(t, test.parts, parts)
 			require.Equal(t, test.params, params)
 		})
 	}
 }
 
+func TestSplitRoutePanics(t *testing.T) {
+	require.Panics(t, func() { splitRoute("/users/:id<int>.json", false) })
+	require.Panics(t, func() { splitRoute("/users/:user-id", true) })
+	require.Panics(t, func() { splitRoute("/:from:to", true) })
+}
+
 func TestMultipleMiddlewaresAndMethodNotAllowed(t *testing.T) {
 	firstMiddleware := func(next HandlerFunc) HandlerFunc {
 		return func(w http.ResponseWriter, req Request) error {
@@ -1052,3 +1257,1040 @@ func TestConflictingPlainAndWilcardRoutes(t *testing.T) {
 func dummyHandler(w http.ResponseWriter, req Request) error {
 	return nil
 }
+
+func TestCustomMethods(t *testing.T) {
+	router := New()
+
+	var method string
+	handler := func(w http.ResponseWriter, req Request) error {
+		method = req.Method
+		return nil
+	}
+
+	router.GET("/files/:name", handler)
+	router.Handle("PROPFIND", "/files/:name", handler)
+	router.Handle("QUERY", "/files/:name", handler)
+	router.Handle("PURGE", "/cache/", handler)
+
+	for _, meth := range []string{"GET", "PROPFIND", "QUERY"} {
+		method = ""
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(meth, "/files/hello", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, meth, method)
+	}
+
+	t.Run("not allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("MKCOL", "/files/hello", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PURGE", "/cache", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusPermanentRedirect, w.Code)
+		require.Equal(t, "/cache/", w.Header().Get("Location"))
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		require.PanicsWithError(t, `route "/files/:name" already handles QUERY`, func() {
+			router.Handle("QUERY", "/files/:name", handler)
+		})
+	})
+
+	t.Run("invalid method", func(t *testing.T) {
+		require.PanicsWithError(t, `invalid HTTP method: "BAD METHOD"`, func() {
+			router.Handle("BAD METHOD", "/files/:name", handler)
+		})
+	})
+}
+
+func TestAllowHeader(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", simpleHandler)
+	router.DELETE("/users/:id", simpleHandler)
+	router.Handle("PROPFIND", "/users/:id", simpleHandler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users/123", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, "GET, DELETE, PROPFIND", w.Header().Get("Allow"))
+
+	t.Run("custom handler", func(t *testing.T) {
+		var allowed []string
+		router := New(WithMethodNotAllowedHandler(func(w http.ResponseWriter, req Request) error {
+			allowed = req.AllowedMethods()
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return nil
+		}))
+		router.GET("/users/:id", simpleHandler)
+		router.PUT("/users/:id", simpleHandler)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/users/123", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		require.Equal(t, []string{"GET", "PUT"}, allowed)
+		require.Equal(t, "GET, PUT", w.Header().Get("Allow"))
+	})
+}
+
+func TestAutoOptions(t *testing.T) {
+	var stack []string
+
+	router := New(WithAutoOptions(), Use(func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, req Request) error {
+			stack = append(stack, req.Method)
+			return next(w, req)
+		}
+	}))
+	router.GET("/users/:id", simpleHandler)
+	router.POST("/users/:id", simpleHandler)
+	router.OPTIONS("/explicit", func(w http.ResponseWriter, req Request) error {
+		w.WriteHeader(http.StatusTeapot)
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("OPTIONS", "/users/123", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "GET, POST, OPTIONS", w.Header().Get("Allow"))
+	require.Equal(t, []string{"OPTIONS"}, stack)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/users/123", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, "GET, POST, OPTIONS", w.Header().Get("Allow"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("OPTIONS", "/explicit", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTeapot, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("OPTIONS", "/not-found", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestImplicitHead(t *testing.T) {
+	router := New(WithImplicitHead())
+	router.GET("/users/:id", func(w http.ResponseWriter, req Request) error {
+		w.Header().Set("X-User", req.Param("id"))
+		_, err := w.Write([]byte("hello world"))
+		return err
+	})
+	router.GET("/explicit", simpleHandler)
+	router.HEAD("/explicit", func(w http.ResponseWriter, req Request) error {
+		w.WriteHeader(http.StatusTeapot)
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("HEAD", "/users/123", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "123", w.Header().Get("X-User"))
+	require.Equal(t, "11", w.Header().Get("Content-Length"))
+	require.Equal(t, "", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("HEAD", "/explicit", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTeapot, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/users/123", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+
+	t.Run("disabled by default", func(t *testing.T) {
+		router := New()
+		router.GET("/users/:id", simpleHandler)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("HEAD", "/users/123", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
+func TestParamConstraints(t *testing.T) {
+	var route string
+	var params map[string]string
+
+	handler := func(w http.ResponseWriter, req Request) error {
+		route = req.Route()
+		params = req.Params().Map()
+		return nil
+	}
+
+	router := New(WithParamConstraint("even", func(s string) bool {
+		n, err := strconv.Atoi(s)
+		return err == nil && n%2 == 0
+	}))
+	router.GET("/users/:id<int>", handler)
+	router.GET("/users/:uuid<uuid>", handler)
+	router.GET("/users/:name", handler)
+	router.GET("/files/:name<[a-z0-9-]+>/raw", handler)
+	router.GET("/numbers/:n<even>", handler)
+
+	type Test struct {
+		path   string
+		route  string
+		params map[string]string
+	}
+
+	for _, test := range []Test{
+		{"/users/123", "/users/:id<int>", map[string]string{"id": "123"}},
+		{"/users/6ba7b810-9dad-11d1-80b4-00c04fd430c8", "/users/:uuid<uuid>", map[string]string{
+			"uuid": "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		}},
+		{"/users/me", "/users/:name", map[string]string{"name": "me"}},
+		{"/files/foo-1/raw", "/files/:name<[a-z0-9-]+>/raw", map[string]string{"name": "foo-1"}},
+		{"/numbers/42", "/numbers/:n<even>", map[string]string{"n": "42"}},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			route, params = "", nil
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, test.route, route)
+			require.Equal(t, test.params, params)
+		})
+	}
+
+	for _, path := range []string{"/files/Foo/raw", "/numbers/41"} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+
+	t.Run("invalid constraint", func(t *testing.T) {
+		require.Panics(t, func() {
+			router.GET("/invalid/:id<[a-z>", handler)
+		})
+	})
+}
+
+func TestMixedParamSegments(t *testing.T) {
+	var route string
+	var params map[string]string
+
+	handler := func(w http.ResponseWriter, req Request) error {
+		route = req.Route()
+		params = req.Params().Map()
+		return nil
+	}
+
+	router := New(WithInlineParams())
+	router.GET("/files/:name", handler)
+	router.GET("/files/:name.json", handler)
+	router.GET("/files/:name.:ext<[a-z]+>/raw", handler)
+	router.GET("/videos", handler)
+	router.GET("/v:version/users", handler)
+	router.GET("/range/:from-:to", handler)
+	router.GET("/items\\:batch", handler)
+
+	type Test struct {
+		path   string
+		route  string
+		params map[string]string
+	}
+
+	for _, test := range []Test{
+		{"/files/hello", "/files/:name", map[string]string{"name": "hello"}},
+		{"/files/hello.json", "/files/:name.json", map[string]string{"name": "hello"}},
+		{"/files/hello.world.json", "/files/:name.json", map[string]string{"name": "hello.world"}},
+		{"/files/hello.xml", "/files/:name", map[string]string{"name": "hello.xml"}},
+		{"/files/hello.txt/raw", "/files/:name.:ext<[a-z]+>/raw", map[string]string{
+			"name": "hello",
+			"ext":  "txt",
+		}},
+		{"/videos", "/videos", map[string]string{}},
+		{"/v2/users", "/v:version/users", map[string]string{"version": "2"}},
+		{"/range/1-10", "/range/:from-:to", map[string]string{"from": "1", "to": "10"}},
+		{"/range/a-b-c", "/range/:from-:to", map[string]string{"from": "a", "to": "b-c"}},
+		{"/items:batch", "/items\\:batch", map[string]string{}},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			route, params = "", nil
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, test.route, route)
+			require.Equal(t, test.params, params)
+		})
+	}
+
+	for _, path := range []string{"/v/users", "/range/1-", "/files/.json/raw"} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+
+	t.Run("adjacent params", func(t *testing.T) {
+		require.Panics(t, func() {
+			router.GET("/:a:b", handler)
+		})
+	})
+
+	t.Run("ambiguous param", func(t *testing.T) {
+		require.Panics(t, func() {
+			router.GET("/users/:user-id", handler)
+		})
+	})
+}
+
+func TestWholeSegmentParams(t *testing.T) {
+	var route string
+	var params map[string]string
+
+	handler := func(w http.ResponseWriter, req Request) error {
+		route = req.Route()
+		params = req.Params().Map()
+		return nil
+	}
+
+	router := New()
+	router.GET("/users/:user-id", handler, WithRouteName("user"))
+	router.GET("/files/:name.json", handler)
+	router.GET("/v1/items:batchGet", handler)
+
+	type Test struct {
+		path   string
+		route  string
+		params map[string]string
+	}
+
+	for _, test := range []Test{
+		{"/users/42", "/users/:user-id", map[string]string{"user-id": "42"}},
+		{"/files/hello", "/files/:name.json", map[string]string{"name.json": "hello"}},
+		{"/v1/items:batchGet", "/v1/items:batchGet", map[string]string{}},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			route, params = "", nil
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, test.route, route)
+			require.Equal(t, test.params, params)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/items", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	url, err := router.URL("user", "user-id", 42)
+	require.NoError(t, err)
+	require.Equal(t, "/users/42", url)
+
+	require.Panics(t, func() {
+		router.GET("/users/:id<int>.json", handler)
+	})
+}
+
+func TestOptionalSegments(t *testing.T) {
+	var route string
+	var params map[string]string
+	var year string
+	var hasYear bool
+
+	handler := func(w http.ResponseWriter, req Request) error {
+		route = req.Route()
+		params = req.Params().Map()
+		year, hasYear = req.Params().Get("year")
+		return nil
+	}
+
+	router := New(WithInlineParams())
+	router.GET("/reports/:year?", handler)
+	router.GET("/reports/:year?/q:quarter<int>?/summary", handler)
+	router.GET("/docs/latest?/intro", handler)
+
+	type Test struct {
+		path   string
+		route  string
+		params map[string]string
+	}
+
+	for _, test := range []Test{
+		{"/reports", "/reports/:year?", map[string]string{}},
+		{"/reports/2024", "/reports/:year?", map[string]string{"year": "2024"}},
+		{"/reports/summary", "/reports/:year?/q:quarter<int>?/summary", map[string]string{}},
+		{"/reports/2024/summary", "/reports/:year?/q:quarter<int>?/summary", map[string]string{
+			"year": "2024",
+		}},
+		{"/reports/q3/summary", "/reports/:year?/q:quarter<int>?/summary", map[string]string{
+			"quarter": "3",
+		}},
+		{"/reports/2024/q3/summary", "/reports/:year?/q:quarter<int>?/summary", map[string]string{
+			"year":    "2024",
+			"quarter": "3",
+		}},
+		{"/docs/intro", "/docs/latest?/intro", map[string]string{}},
+		{"/docs/latest/intro", "/docs/latest?/intro", map[string]string{}},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			route, params = "", nil
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, test.route, route)
+			require.Equal(t, test.params, params)
+			require.Equal(t, test.params["year"], year)
+			_, ok := test.params["year"]
+			require.Equal(t, ok, hasYear)
+		})
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		require.PanicsWithError(t, `routes "/reports/:year?" and "/reports" can't both handle GET`, func() {
+			router.GET("/reports", handler)
+		})
+	})
+}
+
+func TestExpandRoute(t *testing.T) {
+	type Test struct {
+		route  string
+		routes []string
+	}
+
+	for _, test := range []Test{
+		{"/users", []string{"/users"}},
+		{"/:lang?", []string{"/:lang", "/"}},
+		{"/reports/:year?", []string{"/reports/:year", "/reports"}},
+		{"/a?/b?", []string{"/a/b", "/b", "/a", "/"}},
+		{"/a/:b?/c/", []string{"/a/:b/c/", "/a/c/"}},
+	} {
+		t.Run(test.route, func(t *testing.T) {
+			require.Equal(t, test.routes, expandRoute(test.route))
+		})
+	}
+}
+
+func TestMiddleWildcard(t *testing.T) {
+	var route string
+	var params map[string]string
+
+	handler := func(w http.ResponseWriter, req Request) error {
+		route = req.Route()
+		params = req.Params().Map()
+		return nil
+	}
+
+	router := New()
+	router.GET("/repos/*path", handler)
+	router.GET("/repos/*path/blob", handler)
+	router.GET("/repos/*path/blob/*file", handler)
+	router.GET("/repos/:owner/blob", handler)
+	router.GET("/buckets/:b/objects/*key/acl", handler)
+
+	type Test struct {
+		path   string
+		route  string
+		params map[string]string
+	}
+
+	for _, test := range []Test{
+		{"/repos/foo/bar", "/repos/*path", map[string]string{"path": "foo/bar"}},
+		{"/repos/foo/blob", "/repos/:owner/blob", map[string]string{"owner": "foo"}},
+		{"/repos/foo/bar/blob", "/repos/*path/blob", map[string]string{"path": "foo/bar"}},
+		{"/repos/foo/bar/blob/", "/repos/*path/blob/*file", map[string]string{
+			"path": "foo/bar",
+			"file": "",
+		}},
+		{"/repos/foo/bar/blob/a/b.go", "/repos/*path/blob/*file", map[string]string{
+			"path": "foo/bar",
+			"file": "a/b.go",
+		}},
+		{"/buckets/b1/objects/a/b/c/acl", "/buckets/:b/objects/*key/acl", map[string]string{
+			"b":   "b1",
+			"key": "a/b/c",
+		}},
+		{"/buckets/b1/objects/acl/acl", "/buckets/:b/objects/*key/acl", map[string]string{
+			"b":   "b1",
+			"key": "acl",
+		}},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			route, params = "", nil
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, test.route, route)
+			require.Equal(t, test.params, params)
+		})
+	}
+
+	for _, path := range []string{"/buckets/b1/objects/acl", "/buckets/b1/objects/a/b"} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+
+	t.Run("not allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/buckets/b1/objects/a/b/acl", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
+func TestRoutes(t *testing.T) {
+	router := New()
+	router.GET("/", simpleHandler)
+	router.WithGroup("/api", func(g *Group) {
+		g.GET("/users/:id", simpleHandler)
+		g.Handle("PROPFIND", "/files/*path", simpleHandler)
+		g.GET("/reports/:year?", simpleHandler)
+	})
+	router.Host(":tenant.example.com").POST("/login", simpleHandler)
+
+	require.Equal(t, []RouteInfo{
+		{Method: "GET", Path: "/", Params: []string{}},
+		{Method: "GET", Path: "/api/users/:id", Params: []string{"id"}, Group: "/api"},
+		{Method: "PROPFIND", Path: "/api/files/*path", Params: []string{"path"}, Group: "/api"},
+		{Method: "GET", Path: "/api/reports/:year?", Params: []string{"year"}, Group: "/api"},
+		{Method: "POST", Path: "/login", Params: []string{}, Host: ":tenant.example.com"},
+	}, router.Routes())
+}
+
+func TestURL(t *testing.T) {
+	router := New(WithInlineParams())
+	router.WithGroup("/api", func(g *Group) {
+		g.GET("/users/:id<int>", simpleHandler, WithRouteName("user.show"))
+		g.GET("/files/*path", simpleHandler, WithRouteName("file.show"))
+		g.GET("/reports/:year?/summary", simpleHandler, WithRouteName("report"))
+		g.GET("/v:version/items\\:batchGet", simpleHandler, WithRouteName("batch"))
+		g.GET("/codes/:code<[a-z]{3}>", simpleHandler, WithRouteName("code"))
+	})
+	router.GET("/", simpleHandler, WithRouteName("home"))
+
+	// Constraints are compiled when the route is registered.
+	require.NotNil(t, router.names["code"].constraints["code"])
+
+	type Test struct {
+		name   string
+		params []interface{}
+		url    string
+	}
+
+	tests := []Test{
+		{"home", nil, "/"},
+		{"user.show", []interface{}{"id", 42}, "/api/users/42"},
+		{"file.show", []interface{}{"path", "a b/c%d.txt"}, "/api/files/a%20b/c%25d.txt"},
+		{"report", []interface{}{"year", 2024}, "/api/reports/2024/summary"},
+		{"report", nil, "/api/reports/summary"},
+		{"batch", []interface{}{"version", "1"}, "/api/v1/items:batchGet"},
+		{"code", []interface{}{"code", "abc"}, "/api/codes/abc"},
+	}
+	for _, test := range tests {
+		url, err := router.URL(test.name, test.params...)
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.url, url, test.name)
+	}
+
+	errTests := []Test{
+		{name: "unknown"},
+		{name: "user.show"},
+		{name: "user.show", params: []interface{}{"id"}},
+		{name: "user.show", params: []interface{}{"id", "abc"}},
+		{name: "user.show", params: []interface{}{"id", 1, "extra", 2}},
+		{name: "home", params: []interface{}{"id", 1}},
+		{name: "code", params: []interface{}{"code", "abcd"}},
+	}
+	for _, test := range errTests {
+		_, err := router.URL(test.name, test.params...)
+		require.Error(t, err, test.name)
+	}
+
+	require.PanicsWithError(t, `route with name "home" already exists`, func() {
+		router.GET("/home", simpleHandler, WithRouteName("home"))
+	})
+}
+
+func TestRemoveRoute(t *testing.T) {
+	router := New(WithImplicitHead())
+	router.GET("/users/:id", simpleHandler, WithRouteName("user"))
+	router.POST("/users/:id", simpleHandler)
+	router.GET("/files/*path", simpleHandler)
+	router.WithGroup("/api", func(g *Group) {
+		g.GET("/reports/:year?", simpleHandler)
+	})
+
+	require.False(t, router.Remove("DELETE", "/users/:id"))
+	require.False(t, router.Remove("GET", "/users/:name"))
+
+	require.True(t, router.Remove("GET", "/users/:id"))
+	require.False(t, router.Remove("GET", "/users/:id"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, "POST", w.Header().Get("Allow"))
+
+	_, err := router.URL("user", "id", 1)
+	require.Error(t, err)
+
+	require.True(t, router.Remove("POST", "/users/:id"))
+	require.True(t, router.Remove("GET", "/files/*path"))
+	require.True(t, router.Remove("GET", "/api/reports/:year?"))
+
+	for _, path := range []string{"/users/1", "/files/a/b", "/api/reports", "/api/reports/2024"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+	require.Empty(t, router.Routes())
+
+	router.GET("/users/:name", simpleHandler)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/joe", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReplaceRoute(t *testing.T) {
+	router := New()
+
+	var got string
+	handler := func(name string) HandlerFunc {
+		return func(w http.ResponseWriter, req Request) error {
+			got = name + ":" + req.Param("id")
+			return nil
+		}
+	}
+
+	router.GET("/users/:id", handler("old"), WithRouteName("user"))
+	router.Replace("GET", "/users/:id", handler("new"))
+	router.Replace("POST", "/users/:id", handler("post"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, "new:1", got)
+
+	req, _ = http.NewRequest("POST", "/users/2", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, "post:2", got)
+
+	url, err := router.URL("user", "id", 3)
+	require.NoError(t, err)
+	require.Equal(t, "/users/3", url)
+	require.Len(t, router.Routes(), 2)
+}
+
+func TestReplaceRouteFailure(t *testing.T) {
+	router := New()
+	router.GET("/a", simpleHandler, WithRouteName("a"))
+	router.GET("/b", simpleHandler, WithRouteName("taken"))
+	router.GET("/users/:id", simpleHandler, WithRouteName("user"))
+	router.GET("/opt", simpleHandler)
+
+	require.Panics(t, func() {
+		router.Replace("GET", "/a", simpleHandler, WithRouteName("taken"))
+	})
+	// The expansion "/opt/:name" is registered before "/opt" conflicts.
+	require.Panics(t, func() {
+		router.GET("/opt/:name?", simpleHandler)
+	})
+
+	router.GET("/c", simpleHandler)
+	router.GET("/opt/:id", simpleHandler)
+
+	for _, path := range []string{"/a", "/b", "/c", "/users/1", "/opt", "/opt/1"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, path)
+	}
+
+	var names []string
+	for _, route := range router.Routes() {
+		names = append(names, route.Path+" "+route.Name)
+	}
+	require.Equal(t, []string{
+		"/a a", "/b taken", "/users/:id user", "/opt ", "/c ", "/opt/:id ",
+	}, names)
+
+	url, err := router.URL("a")
+	require.NoError(t, err)
+	require.Equal(t, "/a", url)
+}
+
+func TestConcurrentRouteChanges(t *testing.T) {
+	router := New()
+	router.GET("/", simpleHandler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			path := fmt.Sprintf("/items/%d/:id", i)
+			router.GET(path, simpleHandler)
+			router.Host(fmt.Sprintf("host%d.example.com", i)).GET(path, simpleHandler)
+			if i%2 == 0 {
+				router.Remove("GET", path)
+			}
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		req, _ = http.NewRequest("GET", fmt.Sprintf("/items/%d/1", i%100), nil)
+		router.ServeHTTP(w, req)
+	}
+	<-done
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1/1", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/2/1", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	handler := func(w http.ResponseWriter, req Request) error {
+		_, err := fmt.Fprintf(w, "%s %s", req.Route(), req.Param("id"))
+		return err
+	}
+
+	router := New(WithCaseInsensitive(), WithInlineParams())
+	router.GET("/api/users/:id", handler)
+	router.GET("/api/users/:id/Posts", handler)
+	router.GET("/API/exact", handler)
+	router.GET("/files/:id.json", handler)
+
+	type Test struct {
+		path string
+		body string
+	}
+
+	tests := []Test{
+		{"/api/users/Joe", "/api/users/:id Joe"},
+		{"/API/Users/Joe", "/api/users/:id Joe"},
+		{"/Api/USERS/Joe/posts", "/api/users/:id/Posts Joe"},
+		{"/api/exact", "/API/exact "},
+		{"/FILES/Readme.JSON", "/files/:id.json Readme"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.path, nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, test.path)
+		require.Equal(t, test.body, w.Body.String(), test.path)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/API/USERS/1", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	t.Run("trailing slash", func(t *testing.T) {
+		router := New(WithCaseInsensitive())
+		router.GET("/mixed/path", handler)
+		router.GET("/api/users/:id", handler)
+		router.GET("/dir/", handler)
+
+		for path, location := range map[string]string{
+			"/Mixed/Path/":    "/mixed/path",
+			"/MIXED/PATH/":    "/mixed/path",
+			"/api/USERS/x/":   "/api/users/x",
+			"/DIR":            "/dir/",
+			"/Api//Users/Joe": "/api/users/Joe",
+		} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusMovedPermanently, w.Code, path)
+			require.Equal(t, location, w.Header().Get("Location"), path)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		router := New(WithCaseInsensitiveRedirect())
+		router.GET("/api/users/:id/Posts", handler)
+		router.POST("/api/users/:id/Posts", handler)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/API/Users/Joe/posts?page=2", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMovedPermanently, w.Code)
+		require.Equal(t, "/api/users/Joe/Posts?page=2", w.Header().Get("Location"))
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/API/Users/Joe/posts", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusPermanentRedirect, w.Code)
+		require.Equal(t, "/api/users/Joe/Posts", w.Header().Get("Location"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		router := New()
+		router.GET("/api/users/:id", handler)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/API/users/1", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestStaticRoutes(t *testing.T) {
+	var route string
+	handler := func(w http.ResponseWriter, req Request) error {
+		route = req.Route()
+		return nil
+	}
+
+	router := New()
+	router.GET("/", handler)
+	router.GET("/users/new", handler)
+	router.POST("/users/:id", handler)
+	router.GET("/users/:id", handler)
+	router.GET("/files/*path", handler)
+	for i := 0; i < minStaticRoutes; i++ {
+		router.GET(fmt.Sprintf("/static/%d", i), handler)
+	}
+	require.Equal(t, minStaticRoutes+2, router.snapshot.Load().root.numStatic)
+
+	type Test struct {
+		method string
+		path   string
+		route  string
+	}
+
+	tests := []Test{
+		{"GET", "/", "/"},
+		{"GET", "/static/10", "/static/10"},
+		{"GET", "/users/new", "/users/new"},
+		{"POST", "/users/new", "/users/:id"},
+		{"GET", "/users/1", "/users/:id"},
+		{"GET", "/files/", "/files/*path"},
+	}
+	for _, test := range tests {
+		route = ""
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, test.path)
+		require.Equal(t, test.route, route, test.path)
+	}
+
+	router.Remove("GET", "/users/new")
+	require.Equal(t, minStaticRoutes+1, router.snapshot.Load().root.numStatic)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/new", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, "/users/:id", route)
+}
+
+func TestLookupCache(t *testing.T) {
+	var notFound int
+	router := New(
+		WithLookupCache(4),
+		WithNotFoundHandler(func(w http.ResponseWriter, req Request) error {
+			notFound++
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}),
+	)
+	router.GET("/users/", simpleHandler)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/missing", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.NotNil(t, router.snapshot.Load().lookupCache(4).Get("GET", "/missing"))
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/users?page=2", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMovedPermanently, w.Code)
+		require.Equal(t, "/users/?page=2", w.Header().Get("Location"))
+		require.NotNil(t, router.snapshot.Load().lookupCache(4).Get("GET", "/users"))
+	}
+	require.Equal(t, 3, notFound)
+	require.Nil(t, router.snapshot.Load().lookupCache(4).Get("POST", "/missing"))
+
+	// The cache is cleared when routes change.
+	router.GET("/missing", simpleHandler)
+	router.GET("/users", simpleHandler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/missing", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestLookupCacheBucket(t *testing.T) {
+	// All paths go to the same bucket.
+	cache := newLookupCache(lookupWays)
+	paths := []string{"/a", "/b", "/c", "/d"}
+	for _, path := range paths {
+		cache.Set("GET", path, notFoundHandler)
+	}
+	for _, path := range paths {
+		require.NotNil(t, cache.Get("GET", path))
+	}
+	require.Nil(t, cache.Get("POST", "/a"))
+}
+
+func TestParamOffsets(t *testing.T) {
+	var params Params
+	handler := func(w http.ResponseWriter, req Request) error {
+		params = req.Params()
+		return nil
+	}
+
+	router := New(WithCaseInsensitive(), WithInlineParams())
+	router.GET("/users/:id", handler)
+	router.GET("/files/*path", handler)
+	router.GET("/files/:name.:ext/raw", handler)
+	router.GET("/repos/*path/blob/*file", handler)
+	router.GET("/Static/:id", handler)
+	router.GET("/many/:a/:b/:c/:d/:e/:f/:g/:h/:i", handler)
+
+	type Test struct {
+		path       string
+		params     map[string]string
+		hasOffsets bool
+	}
+
+	long := strings.Repeat("a", math.MaxUint16)
+	tests := []Test{
+		{"/files/", map[string]string{"path": ""}, true},
+		{"/users/1", map[string]string{"id": "1"}, true},
+		{"/files/hello.txt/raw", map[string]string{"name": "hello", "ext": "txt"}, true},
+		{"/repos/a/b/blob/c/d", map[string]string{"path": "a/b", "file": "c/d"}, true},
+		{"/STATIC/Joe", map[string]string{"id": "Joe"}, true},
+		{"/many/1/2/3/4/5/6/7/8/9", map[string]string{
+			"a": "1", "b": "2", "c": "3", "d": "4", "e": "5", "f": "6", "g": "7", "h": "8", "i": "9",
+		}, false},
+		{"/users/" + long, map[string]string{"id": long}, false},
+	}
+	for _, test := range tests {
+		params = Params{}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.path, nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, test.hasOffsets, params.hasOffsets())
+
+		require.Equal(t, test.params, params.Map())
+		for name, value := range test.params {
+			require.Equal(t, value, params.ByName(name))
+		}
+
+		// Values must be the same as when the lookup is repeated.
+		noOffsets := params
+		noOffsets.offsets = paramOffsets{}
+		require.Equal(t, noOffsets.Slice(), params.Slice())
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	type handled struct {
+		route         string
+		err           error
+		headerWritten bool
+	}
+	var got handled
+
+	router := New(WithErrorHandler(func(w http.ResponseWriter, req Request, err error) {
+		got = handled{route: req.Route(), err: err, headerWritten: HeaderWritten(w)}
+		DefaultErrorHandler(w, req, err)
+	}))
+	router.GET("/fail", func(w http.ResponseWriter, req Request) error {
+		return errors.New("something failed")
+	})
+	router.GET("/users/:id", func(w http.ResponseWriter, req Request) error {
+		_, err := req.Params().Int("id")
+		return err
+	})
+	router.GET("/partial", func(w http.ResponseWriter, req Request) error {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, "partial")
+		return errors.New("write failed")
+	})
+	router.GET("/ok", simpleHandler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/fail", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, "something failed\n", w.Body.String())
+	require.Equal(t, "/fail", got.route)
+	require.False(t, got.headerWritten)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/abc", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "/users/:id", got.route)
+	var paramErr *ParamError
+	require.True(t, errors.As(got.err, &paramErr))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/partial", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Equal(t, "partial", w.Body.String())
+	require.True(t, got.headerWritten)
+
+	got = handled{}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/ok", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Nil(t, got.err)
+
+	require.False(t, HeaderWritten(httptest.NewRecorder()))
+}
@@ -0,0 +1,199 @@
+package bunrouter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// namedRoute is a route registered with WithRouteName.
+type namedRoute struct {
+	RouteInfo
+	constraints map[string]*paramConstraint // by param name
+	inline      bool                        // see WithInlineParams
+}
+
+func newNamedRoute(info RouteInfo, constraints map[string]func(string) bool, inline bool) *namedRoute {
+	r := &namedRoute{
+		RouteInfo: info,
+		inline:    inline,
+	}
+
+	// Compile the constraints once instead of on each URL call.
+	for _, segment := range strings.Split(info.Path[1:], "/") {
+		segment = strings.TrimSuffix(segment, "?")
+		for i := 0; i < len(segment); i++ {
+			switch c := segment[i]; {
+			case inline && c == '\\':
+				i++
+			case c == ':' && (inline || i == 0):
+				name, spec, end := parseParam(segment, i+1, inline)
+				if spec != "" {
+					if r.constraints == nil {
+						r.constraints = make(map[string]*paramConstraint)
+					}
+					r.constraints[name] = newParamConstraint(spec, constraints)
+				}
+				i = end - 1
+			}
+		}
+	}
+
+	return r
+}
+
+// URL returns the path of the named route with the params replaced by the values.
+// Params are given as name/value pairs and values are formatted with fmt.Sprint,
+// for example, r.URL("user.show", "id", 42) returns "/users/42" for "/users/:id".
+//
+// Param values are escaped with url.PathEscape. Wildcard values are escaped
+// segment by segment so they may contain slashes. Optional segments are omitted
+// when none of their params are given.
+//
+// URL returns an error when the route does not exist, a required param is missing,
+// a param is not used by the route, or a value does not match the param constraint.
+func (r *Router) URL(name string, params ...interface{}) (string, error) {
+	r.mu.Lock()
+	route, ok := r.names[name]
+	r.mu.Unlock()
+
+	if !ok {
+		return "", fmt.Errorf("route with name %q does not exist", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("route %q: params must be name/value pairs", name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("route %q: param name must be a string, got %T", name, params[i])
+		}
+		if _, ok := values[key]; ok {
+			return "", fmt.Errorf("route %q: param %q is given more than once", name, key)
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	s, err := route.build(values)
+	if err != nil {
+		return "", fmt.Errorf("route %q: %w", name, err)
+	}
+	return s, nil
+}
+
+func (r *namedRoute) build(values map[string]string) (string, error) {
+	used := make(map[string]struct{}, len(values))
+
+	var b strings.Builder
+	for _, segment := range strings.Split(r.Path[1:], "/") {
+		optional := len(segment) > 1 && segment[len(segment)-1] == '?'
+		if optional {
+			segment = segment[:len(segment)-1]
+			if !hasParams(segment, values, r.inline) {
+				continue
+			}
+		}
+
+		b.WriteByte('/')
+		if err := r.buildSegment(&b, segment, values, used); err != nil {
+			return "", err
+		}
+	}
+
+	if len(used) < len(values) {
+		for name := range values {
+			if _, ok := used[name]; !ok {
+				return "", fmt.Errorf("unknown param %q", name)
+			}
+		}
+	}
+
+	if b.Len() == 0 {
+		return "/", nil
+	}
+	return b.String(), nil
+}
+
+func (r *namedRoute) buildSegment(
+	b *strings.Builder, segment string, values map[string]string, used map[string]struct{},
+) error {
+	if segment != "" && segment[0] == '*' {
+		name := segment[1:]
+		value, ok := values[name]
+		if !ok {
+			return fmt.Errorf("missing param %q", name)
+		}
+		used[name] = struct{}{}
+
+		for i, part := range strings.Split(value, "/") {
+			if i > 0 {
+				b.WriteByte('/')
+			}
+			b.WriteString(url.PathEscape(part))
+		}
+		return nil
+	}
+
+	for i := 0; i < len(segment); {
+		switch c := segment[i]; {
+		case r.inline && c == '\\' && i+1 < len(segment) && segment[i+1] == ':':
+			b.WriteByte(':')
+			i += 2
+		case c == ':' && (r.inline || i == 0):
+			name, spec, end := parseParam(segment, i+1, r.inline)
+			value, ok := values[name]
+			if !ok {
+				return fmt.Errorf("missing param %q", name)
+			}
+			if value == "" {
+				return fmt.Errorf("param %q can't be empty", name)
+			}
+			if spec != "" && !r.constraints[name].match(value) {
+				return fmt.Errorf("param %q does not match %q: %q", name, spec, value)
+			}
+			used[name] = struct{}{}
+
+			b.WriteString(url.PathEscape(value))
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return nil
+}
+
+// hasParams reports whether any of the segment params is given.
+// Segments without params are always included.
+func hasParams(segment string, values map[string]string, inline bool) bool {
+	if segment[0] == '*' {
+		_, ok := values[segment[1:]]
+		return ok
+	}
+	if !inline {
+		if segment[0] != ':' {
+			return true
+		}
+		name, _, _ := parseParam(segment, 1, false)
+		_, ok := values[name]
+		return ok
+	}
+
+	var found bool
+	for i := 0; i < len(segment); i++ {
+		switch segment[i] {
+		case '\\':
+			i++
+		case ':':
+			found = true
+			name, _, end := parseParam(segment, i+1, true)
+			if _, ok := values[name]; ok {
+				return true
+			}
+			i = end - 1
+		}
+	}
+	return !found
+}
@@ -0,0 +1,413 @@
+package bunrouter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidateStruct checks the struct fields against the rules in the `validate` struct tags,
+// for example, `validate:"required,min=1,max=100"`. Bind calls it after binding the request.
+//
+// Supported rules:
+//
+//   - required - the value is not zero, for example, not an empty string or a nil pointer,
+//   - omitempty - the other rules are skipped when the value is zero,
+//   - min=n and max=n - numbers are compared with n; for strings, slices, and maps the length is compared,
+//   - len=n - the length of a string, slice, or map is exactly n,
+//   - email - the string is an email address, for example, "joe@example.com",
+//   - url - the string is an absolute URL, for example, "https://example.com",
+//   - uuid - the string is a UUID, for example, "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+//   - oneof=a b c - the value is one of the space-separated values.
+//
+// Nested and embedded structs are validated as well. When some fields are invalid,
+// ValidateStruct returns *ValidationError with all invalid fields.
+func ValidateStruct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Errorf("bunrouter: ValidateStruct requires a non-nil struct, got %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("bunrouter: ValidateStruct requires a struct, got %T", v)
+	}
+
+	var errs []*RuleError
+	if err := validateStruct(&errs, rv, "", ""); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// ValidationError is returned by ValidateStruct and Bind when struct fields violate
+// the validation rules. It implements HTTPError with the status code 400 Bad Request
+// and json.Marshaler, so HandlerFunc.ServeHTTP replies with a JSON like:
+//
+//	{
+//	  "message": "request validation failed",
+//	  "errors": [{"field": "email", "rule": "email", "message": "must be a valid email address"}]
+//	}
+type ValidationError struct {
+	Errors []*RuleError
+}
+
+var (
+	_ HTTPError      = (*ValidationError)(nil)
+	_ json.Marshaler = (*ValidationError)(nil)
+)
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("bunrouter: request validation failed: ")
+	for i, err := range e.Errors {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *ValidationError) MarshalJSON() ([]byte, error) {
+	type jsonRuleError struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule"`
+		Message string `json:"message"`
+	}
+
+	errs := make([]jsonRuleError, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = jsonRuleError{
+			Field:   err.Name,
+			Rule:    err.Rule,
+			Message: err.Message,
+		}
+	}
+
+	return json.Marshal(struct {
+		Message string          `json:"message"`
+		Errors  []jsonRuleError `json:"errors"`
+	}{
+		Message: "request validation failed",
+		Errors:  errs,
+	})
+}
+
+// RuleError describes a struct field that violates a validation rule.
+type RuleError struct {
+	Field   string // struct field path, for example, "Address.City"
+	Name    string // field name from the json, path, query, header, or form tag, for example, "address.city"
+	Rule    string // rule name, for example, "min"
+	Param   string // rule param, for example, "1"
+	Message string // human-readable message, for example, "must be at least 1"
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("%s %s", e.Name, e.Message)
+}
+
+//------------------------------------------------------------------------------
+
+// validateField is a struct field with validation rules.
+type validateField struct {
+	index  int
+	field  string
+	name   string
+	rules  []validateRule
+	nested bool // the field is a struct that must be validated as well
+	embed  bool // the field is an embedded struct
+}
+
+type validateRule struct {
+	name  string
+	param string
+	num   float64  // param of min, max, and len
+	oneof []string // param of oneof
+}
+
+// validateFields caches validate fields by the struct type.
+var validateFields sync.Map
+
+func structValidateFields(typ reflect.Type) ([]validateField, error) {
+	if fields, ok := validateFields.Load(typ); ok {
+		return fields.([]validateField), nil
+	}
+
+	var fields []validateField
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+
+		rules, err := parseValidateTag(f.Tag.Get("validate"))
+		if err != nil {
+			return nil, fmt.Errorf("bunrouter: invalid validate tag of field %s: %w", f.Name, err)
+		}
+
+		nested := isNestedStruct(f.Type)
+		if len(rules) == 0 && !nested {
+			continue
+		}
+
+		fields = append(fields, validateField{
+			index:  i,
+			field:  f.Name,
+			name:   fieldName(f),
+			rules:  rules,
+			nested: nested,
+			embed:  f.Anonymous,
+		})
+	}
+
+	validateFields.Store(typ, fields)
+	return fields, nil
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func isNestedStruct(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct && typ != timeType
+}
+
+// fieldName returns the name of the field in the request.
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "path", "query", "header", "form"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+func parseValidateTag(tag string) ([]validateRule, error) {
+	if tag == "" {
+		return nil, nil
+	}
+
+	var rules []validateRule
+	for _, s := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(s, "=")
+		rule := validateRule{name: name, param: param}
+
+		switch name {
+		case "required", "omitempty", "email", "url", "uuid":
+			if param != "" {
+				return nil, fmt.Errorf("rule %q doesn't accept params", name)
+			}
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q requires a number: %w", name, err)
+			}
+			rule.num = n
+		case "oneof":
+			rule.oneof = strings.Fields(param)
+			if len(rule.oneof) == 0 {
+				return nil, fmt.Errorf("rule %q requires values", name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func validateStruct(errs *[]*RuleError, v reflect.Value, fieldPrefix, namePrefix string) error {
+	fields, err := structValidateFields(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for i := range fields {
+		f := &fields[i]
+		fv := v.Field(f.index)
+
+		field, name := fieldPrefix+f.field, namePrefix+f.name
+		if err := validateValue(errs, fv, f.rules, field, name); err != nil {
+			return err
+		}
+
+		if !f.nested {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		// Fields of embedded structs are validated as if they were declared in the parent.
+		nestedField, nestedName := fieldPrefix, namePrefix
+		if !f.embed {
+			nestedField, nestedName = field+".", name+"."
+		}
+		if err := validateStruct(errs, fv, nestedField, nestedName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateValue(errs *[]*RuleError, v reflect.Value, rules []validateRule, field, name string) error {
+	for _, rule := range rules {
+		switch rule.name {
+		case "required":
+			if v.IsZero() {
+				*errs = append(*errs, newRuleError(field, name, rule, "is required"))
+				return nil
+			}
+			continue
+		case "omitempty":
+			if v.IsZero() {
+				return nil
+			}
+			continue
+		}
+
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+
+		msg, err := checkRule(v, &rule)
+		if err != nil {
+			return fmt.Errorf("bunrouter: can't validate field %s: %w", field, err)
+		}
+		if msg != "" {
+			*errs = append(*errs, newRuleError(field, name, rule, msg))
+			return nil
+		}
+	}
+	return nil
+}
+
+func newRuleError(field, name string, rule validateRule, msg string) *RuleError {
+	return &RuleError{
+		Field:   field,
+		Name:    name,
+		Rule:    rule.name,
+		Param:   rule.param,
+		Message: msg,
+	}
+}
+
+// checkRule returns the message when the value violates the rule.
+func checkRule(v reflect.Value, rule *validateRule) (string, error) {
+	switch rule.name {
+	case "min", "max", "len":
+		n, isLen, ok := ruleNumber(v)
+		if !ok {
+			return "", fmt.Errorf("rule %q is not supported for %s", rule.name, v.Type())
+		}
+		if rule.name == "len" && !isLen {
+			return "", fmt.Errorf("rule %q is not supported for %s", rule.name, v.Type())
+		}
+
+		what := "be"
+		if isLen {
+			what = "have length"
+		}
+		switch {
+		case rule.name == "min" && n < rule.num:
+			return fmt.Sprintf("must %s at least %s", what, rule.param), nil
+		case rule.name == "max" && n > rule.num:
+			return fmt.Sprintf("must %s at most %s", what, rule.param), nil
+		case rule.name == "len" && n != rule.num:
+			return fmt.Sprintf("must have length %s", rule.param), nil
+		}
+		return "", nil
+	case "oneof":
+		var s string
+		switch v.Kind() {
+		case reflect.String:
+			s = v.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s = strconv.FormatInt(v.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s = strconv.FormatUint(v.Uint(), 10)
+		default:
+			return "", fmt.Errorf("rule %q is not supported for %s", rule.name, v.Type())
+		}
+		for _, value := range rule.oneof {
+			if s == value {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of: %s", strings.Join(rule.oneof, ", ")), nil
+	}
+
+	if v.Kind() != reflect.String {
+		return "", fmt.Errorf("rule %q is not supported for %s", rule.name, v.Type())
+	}
+	s := v.String()
+
+	switch rule.name {
+	case "email":
+		if !isEmail(s) {
+			return "must be a valid email address", nil
+		}
+	case "url":
+		if !isURL(s) {
+			return "must be a valid URL", nil
+		}
+	case "uuid":
+		if !isUUID(s) {
+			return "must be a valid UUID", nil
+		}
+	}
+	return "", nil
+}
+
+// ruleNumber returns the number compared by min, max, and len rules
+// and whether it is a length.
+func ruleNumber(v reflect.Value) (float64, bool, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true, true
+	}
+	return 0, false, false
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func isURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
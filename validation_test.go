@@ -0,0 +1,139 @@
+package bunrouter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type Address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type createUserRequest struct {
+	Pagination
+
+	Name     string   `json:"name" validate:"required,min=2,max=10"`
+	Email    string   `json:"email" validate:"required,email"`
+	Role     string   `json:"role" validate:"oneof=admin user"`
+	Age      int      `json:"age" validate:"min=18,max=130"`
+	Website  string   `json:"website" validate:"omitempty,url"`
+	Token    *string  `json:"token" validate:"omitempty,uuid"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Code     string   `json:"code" validate:"len=3"`
+	Address  Address  `json:"address"`
+	Manager  *Address `json:"manager"`
+	Internal string
+}
+
+func TestValidateStruct(t *testing.T) {
+	valid := createUserRequest{
+		Name:    "joe",
+		Email:   "joe@example.com",
+		Role:    "admin",
+		Age:     30,
+		Website: "https://example.com",
+		Code:    "abc",
+		Address: Address{City: "Paris"},
+	}
+	require.NoError(t, ValidateStruct(&valid))
+	require.NoError(t, ValidateStruct(valid))
+
+	token := "not-a-uuid"
+	invalid := createUserRequest{
+		Name:    "j",
+		Email:   "joe",
+		Role:    "guest",
+		Age:     3,
+		Website: "example.com",
+		Token:   &token,
+		Tags:    []string{"a", "b", "c"},
+		Code:    "abcd",
+		Manager: &Address{},
+	}
+	err := ValidateStruct(&invalid)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Equal(t, http.StatusBadRequest, validationErr.StatusCode())
+
+	type Result struct {
+		Field, Name, Rule, Message string
+	}
+	var results []Result
+	for _, err := range validationErr.Errors {
+		results = append(results, Result{err.Field, err.Name, err.Rule, err.Message})
+	}
+	require.Equal(t, []Result{
+		{"Name", "name", "min", "must have length at least 2"},
+		{"Email", "email", "email", "must be a valid email address"},
+		{"Role", "role", "oneof", "must be one of: admin, user"},
+		{"Age", "age", "min", "must be at least 18"},
+		{"Website", "website", "url", "must be a valid URL"},
+		{"Token", "token", "uuid", "must be a valid UUID"},
+		{"Tags", "tags", "max", "must have length at most 2"},
+		{"Code", "code", "len", "must have length 3"},
+		{"Address.City", "address.city", "required", "is required"},
+		{"Manager.City", "manager.city", "required", "is required"},
+	}, results)
+
+	require.Error(t, ValidateStruct(nil))
+	require.Error(t, ValidateStruct("string"))
+
+	var badTag struct {
+		Name string `validate:"required,foo"`
+	}
+	err = ValidateStruct(&badTag)
+	require.Error(t, err)
+	require.False(t, errors.As(err, &validationErr))
+
+	var badType struct {
+		Flag bool `validate:"min=1"`
+	}
+	require.Error(t, ValidateStruct(&badType))
+}
+
+func TestBindValidation(t *testing.T) {
+	handler := HandlerFunc(func(w http.ResponseWriter, req Request) error {
+		var dst createUserRequest
+		if err := Bind(req, &dst); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	})
+
+	body := `{"name": "joe", "email": "joe@example.com", "role": "user", "age": 30, "code": "abc", "address": {"city": "Paris"}}`
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req = httptest.NewRequest("POST", "/users", strings.NewReader(`{"name": "joe"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	var resp struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Field   string `json:"field"`
+			Rule    string `json:"rule"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "request validation failed", resp.Message)
+	require.Len(t, resp.Errors, 5)
+	require.Equal(t, "email", resp.Errors[0].Field)
+	require.Equal(t, "required", resp.Errors[0].Rule)
+	require.Equal(t, "is required", resp.Errors[0].Message)
+}